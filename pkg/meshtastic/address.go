@@ -1,6 +1,29 @@
 package meshtastic
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	// BroadcastNodenum is a special node number that means broadcast.
 	BroadcastNodenum uint32 = 0xffffffff
 )
+
+// FormatNodeID formats a node number as a user ID, e.g. "!a1b2c3d4".
+func FormatNodeID(num uint32) string {
+	return fmt.Sprintf("!%08x", num)
+}
+
+// ParseNodeID parses a user ID in "!a1b2c3d4" form into a node number.
+func ParseNodeID(id string) (uint32, error) {
+	if !strings.HasPrefix(id, "!") {
+		return 0, fmt.Errorf("node ID %q must start with '!'", id)
+	}
+	num, err := strconv.ParseUint(id[1:], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid node ID %q: %w", id, err)
+	}
+	return uint32(num), nil
+}
@@ -0,0 +1,221 @@
+// Package admin provides a client for administrative messages sent on ADMIN_APP, which read
+// and change configuration of the local or a remote node.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// DefaultTimeout is the default time to wait for a response to an admin request.
+const DefaultTimeout = 30 * time.Second
+
+// ErrNotAuthorized is returned when the remote node doesn't accept the public key of the
+// local node. The key has to be added to the admin keys in the security config of the node.
+var ErrNotAuthorized = errors.New("not authorized to administer the node")
+
+// Client sends admin messages through a device and waits for responses.
+//
+// The client reads packets from the device while waiting for a response, so it should own
+// the device's transport or receive admin and routing packets through a meshtastic.FrameRouter.
+//
+// Firmware 2.5 and later requires messages to remote nodes to be PKI encrypted and messages
+// changing the node to carry a session passkey. The client requests passkeys when needed
+// and renews them when the node rejects them.
+type Client struct {
+	// Device is the device used to send messages.
+	Device *meshtastic.Device
+	// Dest is the node number of the administered node. Zero means the local node.
+	Dest uint32
+	// ChannelIndex is the channel admin messages are sent on.
+	ChannelIndex uint32
+	// Timeout is the time to wait for a response. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// PublicKey is the public key of the remote node. If set, messages are PKI encrypted.
+	// Otherwise they are sent on ChannelIndex, see UseLegacyChannel.
+	PublicKey []byte
+	// PrivateKey is the private key of the local node from Config_SecurityConfig. If set,
+	// messages are encrypted by the client and encrypted responses are decrypted. Otherwise
+	// the local node does this with its own key.
+	PrivateKey []byte
+	// Sessions stores session passkeys of remote nodes. If nil, the client keeps its own.
+	Sessions *SessionKeys
+
+	lock     sync.Mutex
+	sessions *SessionKeys
+}
+
+// Request sends an admin message and waits for the response.
+func (c *Client) Request(ctx context.Context, msg *proto.AdminMessage) (*proto.AdminMessage, error) {
+	return c.exchange(ctx, msg, true)
+}
+
+// Send sends an admin message which has no response. Messages to remote nodes are sent
+// reliably and Send waits for the acknowledgement.
+func (c *Client) Send(ctx context.Context, msg *proto.AdminMessage) error {
+	_, err := c.exchange(ctx, msg, false)
+	return err
+}
+
+// exchange sends the message and waits for the response or acknowledgement. Messages rejected
+// because of an expired session passkey are sent once more with a new passkey.
+func (c *Client) exchange(ctx context.Context, msg *proto.AdminMessage, wantResponse bool) (*proto.AdminMessage, error) {
+	if !c.isRemote() {
+		packet, err := c.send(ctx, msg, wantResponse)
+		if err != nil || !wantResponse {
+			return nil, err
+		}
+		return c.wait(ctx, packet, wantResponse)
+	}
+
+	for retried := false; ; retried = true {
+		withKey, err := c.withSessionKey(ctx, msg, wantResponse)
+		if err != nil {
+			return nil, err
+		}
+		packet, err := c.send(ctx, withKey, wantResponse)
+		if err != nil {
+			return nil, err
+		}
+		response, err := c.wait(ctx, packet, wantResponse)
+
+		var routingErr meshtastic.RoutingError
+		if errors.As(err, &routingErr) {
+			switch routingErr.Reason {
+			case proto.Routing_ADMIN_BAD_SESSION_KEY:
+				c.sessionKeys().Invalidate(c.dest())
+				if !retried {
+					Logger.Debug("Session passkey rejected, renewing", "node", meshtastic.FormatNodeID(c.dest()))
+					continue
+				}
+			case proto.Routing_ADMIN_PUBLIC_KEY_UNAUTHORIZED:
+				return nil, fmt.Errorf("%w: %w", ErrNotAuthorized, err)
+			}
+		}
+		return response, err
+	}
+}
+
+// wait waits for the response to the packet. If no response is wanted, it waits for
+// the acknowledgement.
+func (c *Client) wait(ctx context.Context, packet *proto.MeshPacket, wantResponse bool) (*proto.AdminMessage, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		reply, err := c.Device.ReceiveFromMesh(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive admin response: %w", err)
+		}
+
+		decoded := reply.GetDecoded()
+		if reply.GetEncrypted() != nil && c.PrivateKey != nil && c.PublicKey != nil && reply.GetFrom() == c.dest() {
+			if decoded, err = meshtastic.DecryptPKI(reply, c.PrivateKey, c.PublicKey); err != nil {
+				continue
+			}
+		}
+		if decoded.GetRequestId() != packet.GetId() {
+			continue
+		}
+
+		switch decoded.GetPortnum() {
+		case proto.PortNum_ADMIN_APP:
+			response := new(proto.AdminMessage)
+			if err = protobuf.Unmarshal(decoded.GetPayload(), response); err != nil {
+				return nil, meshtastic.ErrInvalidPacketFormat
+			}
+			if passkey := response.GetSessionPasskey(); len(passkey) > 0 && c.isRemote() {
+				c.sessionKeys().Put(c.dest(), passkey)
+			}
+			return response, nil
+		case proto.PortNum_ROUTING_APP:
+			routing := new(proto.Routing)
+			if err = protobuf.Unmarshal(decoded.GetPayload(), routing); err != nil {
+				return nil, meshtastic.ErrInvalidPacketFormat
+			}
+			if reason := routing.GetErrorReason(); reason != proto.Routing_NONE {
+				return nil, meshtastic.RoutingError{Reason: reason}
+			}
+			if !wantResponse {
+				return nil, nil
+			}
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, msg *proto.AdminMessage, wantResponse bool) (*proto.MeshPacket, error) {
+	payload, err := protobuf.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling error: %w", err)
+	}
+
+	packet := &proto.MeshPacket{
+		To:      c.dest(),
+		Channel: c.ChannelIndex,
+		PayloadVariant: &proto.MeshPacket_Decoded{
+			Decoded: &proto.Data{
+				Portnum:      proto.PortNum_ADMIN_APP,
+				Payload:      payload,
+				WantResponse: wantResponse,
+			},
+		},
+		WantAck:  c.isRemote(),
+		Priority: proto.MeshPacket_RELIABLE,
+	}
+	if c.isRemote() && c.PublicKey != nil {
+		if err = c.encrypt(packet); err != nil {
+			return nil, err
+		}
+	}
+	if err = c.Device.SendToMesh(ctx, packet); err != nil {
+		return nil, fmt.Errorf("failed to send admin message: %w", err)
+	}
+	return packet, nil
+}
+
+// encrypt marks the packet for PKI encryption by the local node or, if the private key
+// is known, encrypts it.
+func (c *Client) encrypt(packet *proto.MeshPacket) error {
+	packet.Channel = 0
+	packet.PkiEncrypted = true
+	packet.PublicKey = c.PublicKey
+	if c.PrivateKey == nil {
+		return nil
+	}
+
+	// the nonce depends on the sender and ID, so they are set before encryption
+	packet.From = c.Device.NodeID
+	packet.Id = rand.Uint32() | 1
+	encrypted, err := meshtastic.EncryptPKI(packet, c.PrivateKey, c.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt admin message: %w", err)
+	}
+	packet.PayloadVariant = &proto.MeshPacket_Encrypted{Encrypted: encrypted}
+	return nil
+}
+
+func (c *Client) isRemote() bool {
+	return c.dest() != c.Device.NodeID
+}
+
+func (c *Client) dest() uint32 {
+	if c.Dest == 0 {
+		return c.Device.NodeID
+	}
+	return c.Dest
+}
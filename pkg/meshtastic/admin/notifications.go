@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/rtttl"
+)
+
+const (
+	// MaxCannedMessagesLength is the firmware limit of the pipe-separated canned messages.
+	MaxCannedMessagesLength = 200
+	// MaxCannedMessageCount is the maximum number of canned messages shown by the firmware.
+	MaxCannedMessageCount = 50
+	// MaxRingtoneLength is the firmware limit of the RTTTL ringtone.
+	MaxRingtoneLength = 230
+
+	cannedMessageSeparator = "|"
+)
+
+// ErrInvalidCannedMessages is returned for canned messages the firmware would truncate or misread.
+var ErrInvalidCannedMessages = errors.New("invalid canned messages")
+
+// ParseCannedMessages splits the pipe-separated canned messages.
+func ParseCannedMessages(messages string) []string {
+	if messages == "" {
+		return nil
+	}
+	return strings.Split(messages, cannedMessageSeparator)
+}
+
+// FormatCannedMessages joins and validates canned messages.
+func FormatCannedMessages(messages []string) (string, error) {
+	for i, message := range messages {
+		if strings.TrimSpace(message) == "" {
+			return "", fmt.Errorf("%w: message %d is empty", ErrInvalidCannedMessages, i+1)
+		}
+		if strings.Contains(message, cannedMessageSeparator) {
+			return "", fmt.Errorf("%w: message %d contains '%s'", ErrInvalidCannedMessages, i+1, cannedMessageSeparator)
+		}
+	}
+	joined := strings.Join(messages, cannedMessageSeparator)
+	return joined, ValidateCannedMessages(joined)
+}
+
+// ValidateCannedMessages checks the pipe-separated canned messages against firmware limits.
+func ValidateCannedMessages(messages string) error {
+	if len(messages) > MaxCannedMessagesLength {
+		return fmt.Errorf("%w: %d bytes, at most %d are allowed", ErrInvalidCannedMessages, len(messages), MaxCannedMessagesLength)
+	}
+	if count := len(ParseCannedMessages(messages)); count > MaxCannedMessageCount {
+		return fmt.Errorf("%w: %d messages, at most %d are allowed", ErrInvalidCannedMessages, count, MaxCannedMessageCount)
+	}
+	return nil
+}
+
+// ValidateRingtone checks the ringtone syntax and length.
+func ValidateRingtone(ringtone string) error {
+	if len(ringtone) > MaxRingtoneLength {
+		return fmt.Errorf("ringtone is %d bytes long, at most %d are allowed", len(ringtone), MaxRingtoneLength)
+	}
+	return rtttl.Validate(ringtone)
+}
+
+// GetCannedMessageList returns the canned messages as a list.
+func (c *Client) GetCannedMessageList(ctx context.Context) ([]string, error) {
+	messages, err := c.GetCannedMessages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCannedMessages(messages), nil
+}
+
+// SetCannedMessageList changes the canned messages to the list.
+func (c *Client) SetCannedMessageList(ctx context.Context, messages []string) error {
+	joined, err := FormatCannedMessages(messages)
+	if err != nil {
+		return err
+	}
+	return c.SetCannedMessages(ctx, joined)
+}
@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// SessionKeyLifetime is how long a session passkey is used after it was received. The firmware
+// accepts a passkey for 300 seconds after generating it, but keeps handing out the same passkey
+// for the first 150 seconds, so only the rest is guaranteed.
+const SessionKeyLifetime = 150 * time.Second
+
+// LegacyChannelName is the name of the channel used for remote admin by firmware before 2.5.
+const LegacyChannelName = "admin"
+
+// ErrNoLegacyChannel is returned when the local node has no legacy admin channel.
+var ErrNoLegacyChannel = errors.New("no admin channel")
+
+// SessionKeys stores the session passkeys of remote nodes. It is safe for concurrent use,
+// so it can be shared by clients administering the same nodes.
+type SessionKeys struct {
+	lock sync.Mutex
+	keys map[uint32]sessionKey
+}
+
+type sessionKey struct {
+	passkey []byte
+	expires time.Time
+}
+
+// Get returns the passkey of the node, if it has not expired.
+func (s *SessionKeys) Get(node uint32) ([]byte, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key, ok := s.keys[node]
+	if !ok || time.Now().After(key.expires) {
+		return nil, false
+	}
+	return key.passkey, true
+}
+
+// Put stores the passkey received from the node.
+func (s *SessionKeys) Put(node uint32, passkey []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.keys == nil {
+		s.keys = make(map[uint32]sessionKey)
+	}
+	s.keys[node] = sessionKey{passkey: passkey, expires: time.Now().Add(SessionKeyLifetime)}
+}
+
+// Invalidate forgets the passkey of the node.
+func (s *SessionKeys) Invalidate(node uint32) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.keys, node)
+}
+
+// sessionKeys returns the shared session key store or the own one of the client.
+func (c *Client) sessionKeys() *SessionKeys {
+	if c.Sessions != nil {
+		return c.Sessions
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.sessions == nil {
+		c.sessions = new(SessionKeys)
+	}
+	return c.sessions
+}
+
+// withSessionKey returns a copy of the message carrying the session passkey of the node.
+// Messages changing the node require a passkey, so one is requested if there is none.
+func (c *Client) withSessionKey(ctx context.Context, msg *proto.AdminMessage, wantResponse bool) (*proto.AdminMessage, error) {
+	passkey, ok := c.sessionKeys().Get(c.dest())
+	if !ok && !wantResponse {
+		// any get request returns the passkey
+		if _, err := c.GetMetadata(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get session passkey: %w", err)
+		}
+		// firmware before 2.5 has no passkeys, the message is sent without one
+		passkey, _ = c.sessionKeys().Get(c.dest())
+	}
+
+	msg = protobuf.Clone(msg).(*proto.AdminMessage)
+	msg.SessionPasskey = passkey
+	return msg, nil
+}
+
+// UseLegacyChannel finds the legacy admin channel of the local node and sends messages on it.
+// This is needed to administer nodes running firmware before 2.5, which don't support PKI.
+func (c *Client) UseLegacyChannel(ctx context.Context) error {
+	state, err := c.Device.Config().GetState(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get channels: %w", err)
+	}
+	for _, channel := range state.Channels {
+		if channel.GetRole() == proto.Channel_DISABLED {
+			continue
+		}
+		if strings.EqualFold(channel.GetSettings().GetName(), LegacyChannelName) {
+			c.ChannelIndex = uint32(channel.GetIndex())
+			c.PublicKey = nil
+			return nil
+		}
+	}
+	return ErrNoLegacyChannel
+}
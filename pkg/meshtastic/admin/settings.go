@@ -0,0 +1,208 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// ErrUnexpectedResponse is returned when the node responds with a message of another kind.
+var ErrUnexpectedResponse = errors.New("unexpected admin response")
+
+// GetOwner returns the user info of the node owner.
+func (c *Client) GetOwner(ctx context.Context) (*proto.User, error) {
+	response, err := c.Request(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_GetOwnerRequest{GetOwnerRequest: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if owner := response.GetGetOwnerResponse(); owner != nil {
+		return owner, nil
+	}
+	return nil, ErrUnexpectedResponse
+}
+
+// SetOwner changes the user info of the node owner.
+func (c *Client) SetOwner(ctx context.Context, owner *proto.User) error {
+	return c.Send(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_SetOwner{SetOwner: owner},
+	})
+}
+
+// GetConfig returns a single section of the node configuration.
+func (c *Client) GetConfig(ctx context.Context, configType proto.AdminMessage_ConfigType) (*proto.Config, error) {
+	response, err := c.Request(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_GetConfigRequest{GetConfigRequest: configType},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if config := response.GetGetConfigResponse(); config != nil {
+		return config, nil
+	}
+	return nil, ErrUnexpectedResponse
+}
+
+// SetConfig changes a single section of the node configuration.
+func (c *Client) SetConfig(ctx context.Context, config *proto.Config) error {
+	return c.Send(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_SetConfig{SetConfig: config},
+	})
+}
+
+// GetModuleConfig returns a single section of the node module configuration.
+func (c *Client) GetModuleConfig(ctx context.Context, configType proto.AdminMessage_ModuleConfigType) (*proto.ModuleConfig, error) {
+	response, err := c.Request(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_GetModuleConfigRequest{GetModuleConfigRequest: configType},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if config := response.GetGetModuleConfigResponse(); config != nil {
+		return config, nil
+	}
+	return nil, ErrUnexpectedResponse
+}
+
+// SetModuleConfig changes a single section of the node module configuration.
+func (c *Client) SetModuleConfig(ctx context.Context, config *proto.ModuleConfig) error {
+	return c.Send(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_SetModuleConfig{SetModuleConfig: config},
+	})
+}
+
+// GetChannel returns the channel with the given index.
+func (c *Client) GetChannel(ctx context.Context, index uint32) (*proto.Channel, error) {
+	response, err := c.Request(ctx, &proto.AdminMessage{
+		// the index is sent incremented by one, as zero would be treated as not present
+		PayloadVariant: &proto.AdminMessage_GetChannelRequest{GetChannelRequest: index + 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if channel := response.GetGetChannelResponse(); channel != nil {
+		return channel, nil
+	}
+	return nil, ErrUnexpectedResponse
+}
+
+// SetChannel changes the channel at the index given in the channel itself.
+func (c *Client) SetChannel(ctx context.Context, channel *proto.Channel) error {
+	return c.Send(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_SetChannel{SetChannel: channel},
+	})
+}
+
+// GetMetadata returns the device metadata, including the firmware version.
+func (c *Client) GetMetadata(ctx context.Context) (*proto.DeviceMetadata, error) {
+	response, err := c.Request(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_GetDeviceMetadataRequest{GetDeviceMetadataRequest: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if metadata := response.GetGetDeviceMetadataResponse(); metadata != nil {
+		return metadata, nil
+	}
+	return nil, ErrUnexpectedResponse
+}
+
+// GetCannedMessages returns the canned messages of the canned message module, separated by '|'.
+func (c *Client) GetCannedMessages(ctx context.Context) (string, error) {
+	response, err := c.Request(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_GetCannedMessageModuleMessagesRequest{
+			GetCannedMessageModuleMessagesRequest: true,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if variant, ok := response.PayloadVariant.(*proto.AdminMessage_GetCannedMessageModuleMessagesResponse); ok {
+		return variant.GetCannedMessageModuleMessagesResponse, nil
+	}
+	return "", ErrUnexpectedResponse
+}
+
+// SetCannedMessages changes the canned messages of the canned message module. Only the length
+// is checked, use SetCannedMessageList or ValidateCannedMessages for a full check.
+func (c *Client) SetCannedMessages(ctx context.Context, messages string) error {
+	if len(messages) > MaxCannedMessagesLength {
+		return fmt.Errorf("%w: %d bytes, at most %d are allowed", ErrInvalidCannedMessages, len(messages), MaxCannedMessagesLength)
+	}
+	return c.Send(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_SetCannedMessageModuleMessages{SetCannedMessageModuleMessages: messages},
+	})
+}
+
+// GetRingtone returns the RTTTL ringtone of the external notification module.
+func (c *Client) GetRingtone(ctx context.Context) (string, error) {
+	response, err := c.Request(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_GetRingtoneRequest{GetRingtoneRequest: true},
+	})
+	if err != nil {
+		return "", err
+	}
+	if variant, ok := response.PayloadVariant.(*proto.AdminMessage_GetRingtoneResponse); ok {
+		return variant.GetRingtoneResponse, nil
+	}
+	return "", ErrUnexpectedResponse
+}
+
+// SetRingtone changes the RTTTL ringtone of the external notification module. An empty
+// ringtone clears it. Only the length is checked, as firmware plays melodies beyond what
+// ValidateRingtone accepts.
+func (c *Client) SetRingtone(ctx context.Context, ringtone string) error {
+	if len(ringtone) > MaxRingtoneLength {
+		return fmt.Errorf("ringtone is %d bytes long, at most %d are allowed", len(ringtone), MaxRingtoneLength)
+	}
+	return c.Send(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_SetRingtoneMessage{SetRingtoneMessage: ringtone},
+	})
+}
+
+// SetFixedPosition sets the fixed position of the node and enables the fixed position mode.
+func (c *Client) SetFixedPosition(ctx context.Context, position *proto.Position) error {
+	return c.Send(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_SetFixedPosition{SetFixedPosition: position},
+	})
+}
+
+// BeginEditSettings starts a settings transaction. The node applies changes and reboots
+// if needed only after CommitEditSettings.
+func (c *Client) BeginEditSettings(ctx context.Context) error {
+	return c.Send(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_BeginEditSettings{BeginEditSettings: true},
+	})
+}
+
+// CommitEditSettings commits the settings transaction started with BeginEditSettings.
+func (c *Client) CommitEditSettings(ctx context.Context) error {
+	return c.Send(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_CommitEditSettings{CommitEditSettings: true},
+	})
+}
+
+// GetNodeRemoteHardwarePins returns GPIO pins advertised for remote control by nodes in the mesh.
+func (c *Client) GetNodeRemoteHardwarePins(ctx context.Context) ([]*proto.NodeRemoteHardwarePin, error) {
+	response, err := c.Request(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_GetNodeRemoteHardwarePinsRequest{GetNodeRemoteHardwarePinsRequest: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pins := response.GetGetNodeRemoteHardwarePinsResponse(); pins != nil {
+		return pins.GetNodeRemoteHardwarePins(), nil
+	}
+	return nil, ErrUnexpectedResponse
+}
+
+// KeyVerification sends a key verification step to the node. Key verification is driven
+// through the local node, see the keyverify package.
+func (c *Client) KeyVerification(ctx context.Context, verification *proto.KeyVerificationAdmin) error {
+	return c.Send(ctx, &proto.AdminMessage{
+		PayloadVariant: &proto.AdminMessage_KeyVerification{KeyVerification: verification},
+	})
+}
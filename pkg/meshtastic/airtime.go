@@ -0,0 +1,212 @@
+package meshtastic
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	// PreambleLength is the LoRa preamble length in symbols used by Meshtastic.
+	PreambleLength = 16
+	// PacketHeaderSize is the size of the header sent before the payload of every packet.
+	PacketHeaderSize = 16
+	// DutyCycleWindow is the period duty cycle limits are measured over.
+	DutyCycleWindow = time.Hour
+)
+
+// ErrAirtimeExceeded is returned for packets which take longer to send than the whole
+// duty cycle budget.
+var ErrAirtimeExceeded = errors.New("packet exceeds airtime budget")
+
+// dutyCycles holds regions with a duty cycle limit, in percent.
+var dutyCycles = map[proto.Config_LoRaConfig_RegionCode]float64{
+	proto.Config_LoRaConfig_EU_433: 10,
+	proto.Config_LoRaConfig_EU_868: 10,
+	proto.Config_LoRaConfig_UA_433: 10,
+	proto.Config_LoRaConfig_UA_868: 1,
+}
+
+// DutyCycle returns the share of time a node may transmit in the region, from 0 to 1.
+func DutyCycle(region proto.Config_LoRaConfig_RegionCode) float64 {
+	if percent, ok := dutyCycles[region]; ok {
+		return percent / 100
+	}
+	return 1
+}
+
+// SymbolTime returns the duration of a LoRa symbol.
+func (p RadioPreset) SymbolTime() time.Duration {
+	return time.Duration(float64(time.Second) * math.Exp2(float64(p.SpreadingFactor)) / float64(p.Bandwidth))
+}
+
+// TimeOnAir returns the time it takes to transmit a frame of the given size, which includes
+// PacketHeaderSize. The calculation follows the Semtech LoRa modem design guide, with
+// an explicit header and CRC, as used by the firmware. It returns zero for presets
+// failing Validate.
+func (p RadioPreset) TimeOnAir(size int) time.Duration {
+	if p.Validate() != nil {
+		return 0
+	}
+	symbol := p.SymbolTime()
+
+	// low data rate optimization is enabled for symbols longer than 16 ms
+	lowDataRate := 0
+	if symbol > 16*time.Millisecond {
+		lowDataRate = 1
+	}
+	sf := p.SpreadingFactor
+	const crc = 1
+	bits := float64(8*size - 4*sf + 28 + 16*crc)
+	payloadSymbols := 8 + max(math.Ceil(bits/float64(4*(sf-2*lowDataRate)))*float64(p.CodingRate), 0)
+
+	return time.Duration((PreambleLength + 4.25 + payloadSymbols) * float64(symbol))
+}
+
+// PacketTimeOnAir returns the time it takes to transmit the packet.
+func (p RadioPreset) PacketTimeOnAir(packet *proto.MeshPacket) time.Duration {
+	return p.TimeOnAir(PacketHeaderSize + packetPayloadSize(packet))
+}
+
+func packetPayloadSize(packet *proto.MeshPacket) int {
+	if decoded := packet.GetDecoded(); decoded != nil {
+		size := protobuf.Size(decoded)
+		if packet.GetPkiEncrypted() {
+			size += PKIOverhead
+		}
+		return size
+	}
+	return len(packet.GetEncrypted())
+}
+
+// AirtimeLimiter keeps transmissions within the duty cycle of a region, measured over
+// a sliding window. It refuses all transmissions if the preset is invalid. It is safe
+// for concurrent use.
+type AirtimeLimiter struct {
+	Preset RadioPreset
+	Region proto.Config_LoRaConfig_RegionCode
+	// Window is the period duty cycle is measured over. Defaults to DutyCycleWindow.
+	Window time.Duration
+
+	lock  sync.Mutex
+	sends []airtimeUse
+}
+
+type airtimeUse struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// Used returns the airtime used within the current window.
+func (l *AirtimeLimiter) Used() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.used(time.Now())
+}
+
+// Allow reports whether a frame of the given size may be sent now, and if so, records it.
+func (l *AirtimeLimiter) Allow(size int) bool {
+	if l.Preset.Validate() != nil {
+		return false
+	}
+	delay, err := l.reserve(l.Preset.TimeOnAir(size))
+	return err == nil && delay == 0
+}
+
+// Wait blocks until a frame of the given size may be sent and records it. It returns
+// ErrInvalidPreset if the preset is invalid.
+func (l *AirtimeLimiter) Wait(ctx context.Context, size int) error {
+	if err := l.Preset.Validate(); err != nil {
+		return err
+	}
+	airtime := l.Preset.TimeOnAir(size)
+	for {
+		delay, err := l.reserve(airtime)
+		if err != nil || delay == 0 {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve records the airtime if it fits in the budget. Otherwise it returns the time
+// until it will fit.
+func (l *AirtimeLimiter) reserve(airtime time.Duration) (time.Duration, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	window := l.window()
+	budget := time.Duration(DutyCycle(l.Region) * float64(window))
+	if airtime > budget {
+		return 0, ErrAirtimeExceeded
+	}
+
+	now := time.Now()
+	excess := l.used(now) + airtime - budget
+	if excess <= 0 {
+		l.sends = append(l.sends, airtimeUse{at: now, duration: airtime})
+		return 0, nil
+	}
+	// wait until enough of the oldest transmissions leave the window
+	for _, send := range l.sends {
+		excess -= send.duration
+		if excess <= 0 {
+			return send.at.Add(window).Sub(now), nil
+		}
+	}
+	return window, nil
+}
+
+// used drops transmissions outside the window and sums the rest. The lock must be held.
+func (l *AirtimeLimiter) used(now time.Time) time.Duration {
+	start := now.Add(-l.window())
+	i := 0
+	for i < len(l.sends) && !l.sends[i].at.After(start) {
+		i++
+	}
+	l.sends = l.sends[i:]
+
+	var total time.Duration
+	for _, send := range l.sends {
+		total += send.duration
+	}
+	return total
+}
+
+func (l *AirtimeLimiter) window() time.Duration {
+	if l.Window <= 0 {
+		return DutyCycleWindow
+	}
+	return l.Window
+}
+
+// LimitedSender delays packets to keep within the duty cycle of the limiter.
+//
+// Only the first transmission is counted. Retransmissions of packets wanting an
+// acknowledgement and rebroadcasts by other nodes are not.
+type LimitedSender struct {
+	Sender  PacketSender
+	Limiter *AirtimeLimiter
+}
+
+var _ PacketSender = &LimitedSender{}
+
+// SendToMesh waits for enough airtime and sends the packet.
+func (s *LimitedSender) SendToMesh(ctx context.Context, packet *proto.MeshPacket) error {
+	if err := s.Limiter.Wait(ctx, PacketHeaderSize+packetPayloadSize(packet)); err != nil {
+		return err
+	}
+	return s.Sender.SendToMesh(ctx, packet)
+}
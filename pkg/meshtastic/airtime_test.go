@@ -0,0 +1,120 @@
+package meshtastic
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+func TestTimeOnAir(t *testing.T) {
+	// values of the Semtech LoRa calculator with a 16 symbol preamble, explicit header,
+	// CRC and automatic low data rate optimization
+	tests := []struct {
+		preset RadioPreset
+		size   int
+		want   time.Duration
+	}{
+		{PresetShortFast, 16, 29824 * time.Microsecond},
+		{PresetShortFast, 50, 52864 * time.Microsecond},
+		{PresetShortFast, 255, 203904 * time.Microsecond},
+		{PresetLongFast, 16, 354304 * time.Microsecond},
+		{PresetLongFast, 50, 641024 * time.Microsecond},
+		{PresetLongFast, 255, 2156544 * time.Microsecond},
+		// low data rate optimization
+		{PresetLongSlow, 50, 3547136 * time.Microsecond},
+		{RadioPreset{SpreadingFactor: 7, Bandwidth: 125000, CodingRate: 5}, 10, 49408 * time.Microsecond},
+	}
+	for _, tt := range tests {
+		got := tt.preset.TimeOnAir(tt.size)
+		if diff := got - tt.want; diff < -time.Microsecond || diff > time.Microsecond {
+			t.Errorf("%s SF%d BW%d: TimeOnAir(%d) = %v, want %v",
+				tt.preset.Name, tt.preset.SpreadingFactor, tt.preset.Bandwidth, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestLoRaPreset(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *proto.Config_LoRaConfig
+		want    RadioPreset
+		wantErr bool
+	}{
+		{
+			name:   "preset",
+			config: &proto.Config_LoRaConfig{UsePreset: true, ModemPreset: proto.Config_LoRaConfig_LONG_FAST},
+			want:   PresetLongFast,
+		},
+		{
+			name:    "unknown preset",
+			config:  &proto.Config_LoRaConfig{UsePreset: true, ModemPreset: 100},
+			wantErr: true,
+		},
+		{
+			name:   "custom",
+			config: &proto.Config_LoRaConfig{SpreadFactor: 10, Bandwidth: 62, CodingRate: 6},
+			want:   RadioPreset{Name: "Custom", SpreadingFactor: 10, Bandwidth: 62500, CodingRate: 6},
+		},
+		{
+			name:    "custom without settings",
+			config:  &proto.Config_LoRaConfig{},
+			wantErr: true,
+		},
+		{
+			name:    "custom without coding rate",
+			config:  &proto.Config_LoRaConfig{SpreadFactor: 10, Bandwidth: 125},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoRaPreset(tt.config)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPreset) {
+					t.Errorf("got error %v, want ErrInvalidPreset", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAirtimeLimiterInvalidPreset(t *testing.T) {
+	limiter := &AirtimeLimiter{Region: proto.Config_LoRaConfig_EU_868}
+	if limiter.Allow(50) {
+		t.Error("limiter without a preset allowed a transmission")
+	}
+	if err := limiter.Wait(context.Background(), 50); !errors.Is(err, ErrInvalidPreset) {
+		t.Errorf("got error %v, want ErrInvalidPreset", err)
+	}
+}
+
+func TestAirtimeLimiterBudget(t *testing.T) {
+	// 10% of one second fits a single 52.864 ms ShortFast frame
+	limiter := &AirtimeLimiter{Preset: PresetShortFast, Region: proto.Config_LoRaConfig_EU_868, Window: time.Second}
+	if !limiter.Allow(50) {
+		t.Fatal("first frame not allowed")
+	}
+	if limiter.Allow(50) {
+		t.Fatal("frame over the budget allowed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := limiter.Wait(ctx, 50); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("waited %v, want the first frame to leave the window", elapsed)
+	}
+}
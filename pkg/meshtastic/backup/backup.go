@@ -0,0 +1,141 @@
+// Package backup captures a complete off-device backup of a node and restores it to the same
+// or a replacement node.
+package backup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/admin"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// Backup holds everything needed to restore a node's settings.
+type Backup struct {
+	// FirmwareVersion is the firmware version of the node at capture time.
+	FirmwareVersion string
+	// CreatedAt is the capture time.
+	CreatedAt time.Time
+	// NodeNum is the node number of the backed up node.
+	NodeNum uint32
+
+	Config         *proto.LocalConfig
+	ModuleConfig   *proto.LocalModuleConfig
+	Channels       *proto.ChannelFile
+	Owner          *proto.User
+	FixedPosition  *proto.Position
+	CannedMessages string
+	Ringtone       string
+}
+
+// Capture reads the node's configuration, channels, owner and module data into a backup.
+// Canned messages and the ringtone are skipped with a warning if the node does not provide them.
+func Capture(ctx context.Context, device *meshtastic.Device) (*Backup, error) {
+	state, err := device.Config().GetState(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device state: %w", err)
+	}
+
+	b := &Backup{
+		FirmwareVersion: state.Device.GetFirmwareVersion(),
+		CreatedAt:       time.Now().UTC(),
+		NodeNum:         state.MyInfo.GetMyNodeNum(),
+		Config:          state.Config,
+		ModuleConfig:    state.ModuleConfig,
+		Channels:        &proto.ChannelFile{Channels: state.Channels},
+	}
+
+	if node, ok := state.CurrentNodeInfo(); ok {
+		b.Owner = node.GetUser()
+		if state.Config.GetPosition().GetFixedPosition() && node.GetPosition() != nil {
+			b.FixedPosition = node.GetPosition()
+		}
+	}
+
+	client := &admin.Client{Device: device}
+	if b.CannedMessages, err = client.GetCannedMessages(ctx); err != nil {
+		Logger.Warn("Canned messages are not backed up", "error", err)
+	}
+	if b.Ringtone, err = client.GetRingtone(ctx); err != nil {
+		Logger.Warn("Ringtone is not backed up", "error", err)
+	}
+
+	return b, nil
+}
+
+// RestoreOptions control which parts of a backup are restored.
+type RestoreOptions struct {
+	// SkipSecurityKeys keeps the node's own key pair. Use it when the original node is still
+	// in service, as two nodes with the same keys confuse the mesh.
+	SkipSecurityKeys bool
+	// SkipOwner keeps the node's own user info.
+	SkipOwner bool
+}
+
+// Restore writes the backup to the node in a single settings transaction.
+// The node may reboot after the transaction is committed.
+func Restore(ctx context.Context, device *meshtastic.Device, b *Backup, opts RestoreOptions) error {
+	client := &admin.Client{Device: device}
+	if err := client.BeginEditSettings(ctx); err != nil {
+		return err
+	}
+
+	if b.Owner != nil && !opts.SkipOwner {
+		owner := protobuf.Clone(b.Owner).(*proto.User)
+		owner.Id, owner.Macaddr = "", nil // belong to the hardware
+		if opts.SkipSecurityKeys {
+			owner.PublicKey = nil
+		}
+		if err := client.SetOwner(ctx, owner); err != nil {
+			return fmt.Errorf("failed to restore owner: %w", err)
+		}
+	}
+
+	if b.Config != nil {
+		for _, section := range meshtastic.SplitConfig(b.Config) {
+			if section.GetSecurity() != nil && opts.SkipSecurityKeys {
+				security := protobuf.Clone(section.GetSecurity()).(*proto.Config_SecurityConfig)
+				security.PublicKey, security.PrivateKey = nil, nil
+				section.PayloadVariant = &proto.Config_Security{Security: security}
+			}
+			if err := client.SetConfig(ctx, section); err != nil {
+				return fmt.Errorf("failed to restore config: %w", err)
+			}
+		}
+	}
+
+	if b.ModuleConfig != nil {
+		for _, section := range meshtastic.SplitModuleConfig(b.ModuleConfig) {
+			if err := client.SetModuleConfig(ctx, section); err != nil {
+				return fmt.Errorf("failed to restore module config: %w", err)
+			}
+		}
+	}
+
+	for _, channel := range b.Channels.GetChannels() {
+		if err := client.SetChannel(ctx, channel); err != nil {
+			return fmt.Errorf("failed to restore channel %d: %w", channel.GetIndex(), err)
+		}
+	}
+
+	if b.CannedMessages != "" {
+		if err := client.SetCannedMessages(ctx, b.CannedMessages); err != nil {
+			return fmt.Errorf("failed to restore canned messages: %w", err)
+		}
+	}
+	if b.Ringtone != "" {
+		if err := client.SetRingtone(ctx, b.Ringtone); err != nil {
+			return fmt.Errorf("failed to restore ringtone: %w", err)
+		}
+	}
+	if b.FixedPosition != nil {
+		if err := client.SetFixedPosition(ctx, b.FixedPosition); err != nil {
+			return fmt.Errorf("failed to restore fixed position: %w", err)
+		}
+	}
+
+	return client.CommitEditSettings(ctx)
+}
@@ -0,0 +1,237 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	// FormatName identifies backup files written by this package.
+	FormatName = "meshtastic-go-backup"
+	// FormatVersion is the version of the backup file layout.
+	FormatVersion = 1
+
+	kdfName       = "pbkdf2-sha256"
+	kdfIterations = 600_000
+	// iterations accepted from files, fewer are too weak and more make reading hang
+	minKDFIterations = 100_000
+	maxKDFIterations = 10_000_000
+	saltSize         = 16
+	keySize          = 32
+)
+
+var (
+	// ErrPassphraseRequired is returned when reading an encrypted backup without a passphrase.
+	ErrPassphraseRequired = errors.New("backup is encrypted, passphrase is required")
+	// ErrWrongPassphrase is returned when the backup cannot be decrypted with the passphrase.
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted backup")
+	// ErrUnsupportedFormat is returned for files of unknown format or newer version.
+	ErrUnsupportedFormat = errors.New("unsupported backup format")
+)
+
+// file is the on-disk layout of a backup. The header stays readable when the contents are encrypted.
+type file struct {
+	Format          string          `json:"format"`
+	Version         int             `json:"version"`
+	FirmwareVersion string          `json:"firmware_version"`
+	CreatedAt       time.Time       `json:"created_at"`
+	NodeNum         uint32          `json:"node_num"`
+	Encryption      *encryption     `json:"encryption,omitempty"`
+	Contents        json.RawMessage `json:"contents,omitempty"`
+	Ciphertext      []byte          `json:"ciphertext,omitempty"`
+}
+
+type encryption struct {
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+}
+
+// contents holds the protobuf parts of a backup encoded with protojson.
+type contents struct {
+	Config         json.RawMessage `json:"config,omitempty"`
+	ModuleConfig   json.RawMessage `json:"module_config,omitempty"`
+	Channels       json.RawMessage `json:"channels,omitempty"`
+	Owner          json.RawMessage `json:"owner,omitempty"`
+	FixedPosition  json.RawMessage `json:"fixed_position,omitempty"`
+	CannedMessages string          `json:"canned_messages,omitempty"`
+	Ringtone       string          `json:"ringtone,omitempty"`
+}
+
+// Write encodes the backup into w. If the passphrase is not empty, the contents are encrypted
+// with AES-256-GCM using a key derived from the passphrase.
+func Write(w io.Writer, b *Backup, passphrase string) error {
+	body, err := encodeContents(b)
+	if err != nil {
+		return err
+	}
+
+	f := file{
+		Format:          FormatName,
+		Version:         FormatVersion,
+		FirmwareVersion: b.FirmwareVersion,
+		CreatedAt:       b.CreatedAt,
+		NodeNum:         b.NodeNum,
+	}
+	if passphrase == "" {
+		f.Contents = body
+	} else {
+		f.Encryption = &encryption{KDF: kdfName, Iterations: kdfIterations, Salt: make([]byte, saltSize)}
+		if _, err = rand.Read(f.Encryption.Salt); err != nil {
+			return err
+		}
+		aead, err := newAEAD(passphrase, f.Encryption)
+		if err != nil {
+			return err
+		}
+		f.Encryption.Nonce = make([]byte, aead.NonceSize())
+		if _, err = rand.Read(f.Encryption.Nonce); err != nil {
+			return err
+		}
+		f.Ciphertext = aead.Seal(nil, f.Encryption.Nonce, body, header(f))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(f)
+}
+
+// Read decodes a backup from r. The passphrase is required for encrypted backups.
+func Read(r io.Reader, passphrase string) (*Backup, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse backup: %w", err)
+	}
+	if f.Format != FormatName || f.Version < 1 || f.Version > FormatVersion {
+		return nil, ErrUnsupportedFormat
+	}
+
+	body := []byte(f.Contents)
+	if f.Encryption != nil {
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		if f.Encryption.KDF != kdfName {
+			return nil, ErrUnsupportedFormat
+		}
+		aead, err := newAEAD(passphrase, f.Encryption)
+		if err != nil {
+			return nil, err
+		}
+		if len(f.Encryption.Nonce) != aead.NonceSize() {
+			return nil, ErrWrongPassphrase
+		}
+		if body, err = aead.Open(nil, f.Encryption.Nonce, f.Ciphertext, header(f)); err != nil {
+			return nil, ErrWrongPassphrase
+		}
+	}
+
+	b := &Backup{
+		FirmwareVersion: f.FirmwareVersion,
+		CreatedAt:       f.CreatedAt,
+		NodeNum:         f.NodeNum,
+	}
+	if err := decodeContents(body, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// header returns the unencrypted file fields authenticated along with the encrypted contents.
+func header(f file) []byte {
+	return fmt.Appendf(nil, "%s/%d/%s/%d/%d", f.Format, f.Version, f.FirmwareVersion, f.NodeNum, f.CreatedAt.Unix())
+}
+
+func newAEAD(passphrase string, params *encryption) (cipher.AEAD, error) {
+	if params.Iterations < minKDFIterations || params.Iterations > maxKDFIterations {
+		return nil, fmt.Errorf("%w: %d key derivation iterations", ErrUnsupportedFormat, params.Iterations)
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, params.Salt, params.Iterations, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encodeContents(b *Backup) ([]byte, error) {
+	c := contents{CannedMessages: b.CannedMessages, Ringtone: b.Ringtone}
+	parts := []struct {
+		dst *json.RawMessage
+		msg protobuf.Message
+	}{
+		{&c.Config, b.Config},
+		{&c.ModuleConfig, b.ModuleConfig},
+		{&c.Channels, b.Channels},
+		{&c.Owner, b.Owner},
+		{&c.FixedPosition, b.FixedPosition},
+	}
+	for _, part := range parts {
+		if !part.msg.ProtoReflect().IsValid() {
+			continue // nil message
+		}
+		data, err := protojson.Marshal(part.msg)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling error: %w", err)
+		}
+		*part.dst = data
+	}
+	return json.Marshal(c)
+}
+
+func decodeContents(body []byte, b *Backup) error {
+	var c contents
+	if err := json.Unmarshal(body, &c); err != nil {
+		return fmt.Errorf("failed to parse backup contents: %w", err)
+	}
+	b.CannedMessages, b.Ringtone = c.CannedMessages, c.Ringtone
+
+	opts := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if c.Config != nil {
+		b.Config = new(proto.LocalConfig)
+		if err := opts.Unmarshal(c.Config, b.Config); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+	}
+	if c.ModuleConfig != nil {
+		b.ModuleConfig = new(proto.LocalModuleConfig)
+		if err := opts.Unmarshal(c.ModuleConfig, b.ModuleConfig); err != nil {
+			return fmt.Errorf("invalid module config: %w", err)
+		}
+	}
+	if c.Channels != nil {
+		b.Channels = new(proto.ChannelFile)
+		if err := opts.Unmarshal(c.Channels, b.Channels); err != nil {
+			return fmt.Errorf("invalid channels: %w", err)
+		}
+	}
+	if c.Owner != nil {
+		b.Owner = new(proto.User)
+		if err := opts.Unmarshal(c.Owner, b.Owner); err != nil {
+			return fmt.Errorf("invalid owner: %w", err)
+		}
+	}
+	if c.FixedPosition != nil {
+		b.FixedPosition = new(proto.Position)
+		if err := opts.Unmarshal(c.FixedPosition, b.FixedPosition); err != nil {
+			return fmt.Errorf("invalid fixed position: %w", err)
+		}
+	}
+	return nil
+}
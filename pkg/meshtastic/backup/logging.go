@@ -0,0 +1,5 @@
+package backup
+
+import "github.com/exepirit/meshtastic-go/internal/log"
+
+var Logger log.Logger = new(log.NOOPLogger)
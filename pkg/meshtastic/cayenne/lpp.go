@@ -0,0 +1,214 @@
+// Package cayenne encodes and decodes Cayenne Low Power Payload (LPP) sensor data
+// carried on CAYENNE_APP.
+package cayenne
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Type is an LPP data type.
+type Type uint8
+
+// Standard LPP data types.
+const (
+	TypeDigitalInput  Type = 0
+	TypeDigitalOutput Type = 1
+	TypeAnalogInput   Type = 2
+	TypeAnalogOutput  Type = 3
+	TypeGenericSensor Type = 100
+	TypeIlluminance   Type = 101
+	TypePresence      Type = 102
+	TypeTemperature   Type = 103
+	TypeHumidity      Type = 104
+	TypeAccelerometer Type = 113
+	TypeBarometer     Type = 115
+	TypeVoltage       Type = 116
+	TypeCurrent       Type = 117
+	TypeFrequency     Type = 118
+	TypePercentage    Type = 120
+	TypeAltitude      Type = 121
+	TypeConcentration Type = 125
+	TypePower         Type = 128
+	TypeDistance      Type = 130
+	TypeEnergy        Type = 131
+	TypeDirection     Type = 132
+	TypeUnixTime      Type = 133
+	TypeGyrometer     Type = 134
+	TypeColour        Type = 135
+	TypeGPS           Type = 136
+	TypeSwitch        Type = 142
+)
+
+// field describes a single value of a data type.
+type field struct {
+	size   int
+	signed bool
+	// divisor converts the raw integer into the value
+	divisor float64
+}
+
+// typeInfo describes the binary layout of a data type.
+type typeInfo struct {
+	name   string
+	unit   string
+	fields []field
+}
+
+var types = map[Type]typeInfo{
+	TypeDigitalInput:  {"digital_input", "", fields(1, 1, false, 1)},
+	TypeDigitalOutput: {"digital_output", "", fields(1, 1, false, 1)},
+	TypeAnalogInput:   {"analog_input", "", fields(1, 2, true, 100)},
+	TypeAnalogOutput:  {"analog_output", "", fields(1, 2, true, 100)},
+	TypeGenericSensor: {"generic_sensor", "", fields(1, 4, false, 1)},
+	TypeIlluminance:   {"illuminance", "lx", fields(1, 2, false, 1)},
+	TypePresence:      {"presence", "", fields(1, 1, false, 1)},
+	TypeTemperature:   {"temperature", "°C", fields(1, 2, true, 10)},
+	TypeHumidity:      {"humidity", "%", fields(1, 1, false, 2)},
+	TypeAccelerometer: {"accelerometer", "G", fields(3, 2, true, 1000)},
+	TypeBarometer:     {"barometer", "hPa", fields(1, 2, false, 10)},
+	TypeVoltage:       {"voltage", "V", fields(1, 2, false, 100)},
+	TypeCurrent:       {"current", "A", fields(1, 2, false, 1000)},
+	TypeFrequency:     {"frequency", "Hz", fields(1, 4, false, 1)},
+	TypePercentage:    {"percentage", "%", fields(1, 1, false, 1)},
+	TypeAltitude:      {"altitude", "m", fields(1, 2, true, 1)},
+	TypeConcentration: {"concentration", "ppm", fields(1, 2, false, 1)},
+	TypePower:         {"power", "W", fields(1, 2, false, 1)},
+	TypeDistance:      {"distance", "m", fields(1, 4, false, 1000)},
+	TypeEnergy:        {"energy", "kWh", fields(1, 4, false, 1000)},
+	TypeDirection:     {"direction", "°", fields(1, 2, false, 1)},
+	TypeUnixTime:      {"unix_time", "s", fields(1, 4, false, 1)},
+	TypeGyrometer:     {"gyrometer", "°/s", fields(3, 2, true, 100)},
+	TypeColour:        {"colour", "", fields(3, 1, false, 1)},
+	TypeGPS: {"gps", "", []field{
+		{size: 3, signed: true, divisor: 10000}, // latitude, degrees
+		{size: 3, signed: true, divisor: 10000}, // longitude, degrees
+		{size: 3, signed: true, divisor: 100},   // altitude, meters
+	}},
+	TypeSwitch: {"switch", "", fields(1, 1, false, 1)},
+}
+
+func fields(count, size int, signed bool, divisor float64) []field {
+	result := make([]field, count)
+	for i := range result {
+		result[i] = field{size: size, signed: signed, divisor: divisor}
+	}
+	return result
+}
+
+var (
+	// ErrUnknownType is returned for data types unknown to the codec. The rest of the payload
+	// can't be decoded, as the size of the unknown value is not known.
+	ErrUnknownType = errors.New("unknown LPP data type")
+	// ErrTruncated is returned when the payload ends in the middle of a reading.
+	ErrTruncated = errors.New("truncated LPP payload")
+	// ErrValueCount is returned when a reading has a wrong number of values for its type.
+	ErrValueCount = errors.New("wrong number of LPP values")
+)
+
+// String returns the name of the data type.
+func (t Type) String() string {
+	if info, ok := types[t]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("type_%d", uint8(t))
+}
+
+// Unit returns the unit of the values of the data type, if any.
+func (t Type) Unit() string {
+	return types[t].unit
+}
+
+// Reading is a single LPP value set of a sensor channel.
+type Reading struct {
+	// Channel distinguishes sensors of the same type on a device.
+	Channel uint8
+	Type    Type
+	// Values holds the scaled values: one for most types, three for accelerometer, gyrometer
+	// and colour (x, y, z or r, g, b), and latitude, longitude and altitude for GPS.
+	Values []float64
+}
+
+// Value returns the first value of the reading.
+func (r Reading) Value() float64 {
+	if len(r.Values) == 0 {
+		return 0
+	}
+	return r.Values[0]
+}
+
+// Decode decodes an LPP payload. Readings decoded before an error are returned with it.
+func Decode(payload []byte) ([]Reading, error) {
+	var readings []Reading
+	for len(payload) > 0 {
+		if len(payload) < 2 {
+			return readings, ErrTruncated
+		}
+		reading := Reading{Channel: payload[0], Type: Type(payload[1])}
+		info, ok := types[reading.Type]
+		if !ok {
+			return readings, fmt.Errorf("%w: %d", ErrUnknownType, payload[1])
+		}
+		payload = payload[2:]
+
+		for _, f := range info.fields {
+			if len(payload) < f.size {
+				return readings, ErrTruncated
+			}
+			reading.Values = append(reading.Values, f.decode(payload[:f.size]))
+			payload = payload[f.size:]
+		}
+		readings = append(readings, reading)
+	}
+	return readings, nil
+}
+
+// Encode encodes readings into an LPP payload. Values are rounded to the resolution
+// of their type and clamped to its range.
+func Encode(readings ...Reading) ([]byte, error) {
+	var payload []byte
+	for _, reading := range readings {
+		info, ok := types[reading.Type]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownType, uint8(reading.Type))
+		}
+		if len(reading.Values) != len(info.fields) {
+			return nil, fmt.Errorf("%w: %s needs %d", ErrValueCount, reading.Type, len(info.fields))
+		}
+
+		payload = append(payload, reading.Channel, byte(reading.Type))
+		for i, f := range info.fields {
+			payload = f.encode(payload, reading.Values[i])
+		}
+	}
+	return payload, nil
+}
+
+func (f field) decode(data []byte) float64 {
+	var raw uint64
+	for _, b := range data {
+		raw = raw<<8 | uint64(b)
+	}
+	if f.signed {
+		shift := 64 - 8*f.size
+		return float64(int64(raw<<shift)>>shift) / f.divisor
+	}
+	return float64(raw) / f.divisor
+}
+
+func (f field) encode(dst []byte, value float64) []byte {
+	bits := 8 * f.size
+	scaled := math.Round(value * f.divisor)
+	var lo, hi float64
+	if f.signed {
+		lo, hi = -math.Exp2(float64(bits-1)), math.Exp2(float64(bits-1))-1
+	} else {
+		lo, hi = 0, math.Exp2(float64(bits))-1
+	}
+	raw := uint64(int64(min(max(scaled, lo), hi)))
+	for i := f.size - 1; i >= 0; i-- {
+		dst = append(dst, byte(raw>>(8*i)))
+	}
+	return dst
+}
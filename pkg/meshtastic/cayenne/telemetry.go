@@ -0,0 +1,151 @@
+package cayenne
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// ToTelemetry converts readings to telemetry messages, so LPP sensors can be handled like
+// Meshtastic ones. Environment and air quality values are returned as separate messages.
+// Readings without a telemetry field are skipped. If several channels report the same
+// type, the first one is used.
+func ToTelemetry(readings []Reading, at time.Time) []*proto.Telemetry {
+	var (
+		env = new(proto.EnvironmentMetrics)
+		air = new(proto.AirQualityMetrics)
+	)
+	for _, reading := range readings {
+		value := reading.Value()
+		switch reading.Type {
+		case TypeTemperature:
+			setOnce(&env.Temperature, float32(value))
+		case TypeHumidity:
+			setOnce(&env.RelativeHumidity, float32(value))
+		case TypeBarometer:
+			setOnce(&env.BarometricPressure, float32(value))
+		case TypeVoltage:
+			setOnce(&env.Voltage, float32(value))
+		case TypeCurrent:
+			setOnce(&env.Current, float32(value))
+		case TypeIlluminance:
+			setOnce(&env.Lux, float32(value))
+		case TypeDistance:
+			setOnce(&env.Distance, float32(value*1000)) // telemetry distance is in mm
+		case TypeConcentration:
+			// LPP concentration is used by CO2 sensors in practice
+			setOnce(&air.Co2, uint32(value))
+		}
+	}
+
+	var telemetry []*proto.Telemetry
+	timestamp := uint32(at.Unix())
+	if protobuf.Size(env) > 0 {
+		telemetry = append(telemetry, &proto.Telemetry{
+			Time:    timestamp,
+			Variant: &proto.Telemetry_EnvironmentMetrics{EnvironmentMetrics: env},
+		})
+	}
+	if protobuf.Size(air) > 0 {
+		telemetry = append(telemetry, &proto.Telemetry{
+			Time:    timestamp,
+			Variant: &proto.Telemetry_AirQualityMetrics{AirQualityMetrics: air},
+		})
+	}
+	return telemetry
+}
+
+// ToPosition returns the position of the first GPS reading, if any.
+func ToPosition(readings []Reading, at time.Time) (*proto.Position, bool) {
+	for _, reading := range readings {
+		if reading.Type != TypeGPS || len(reading.Values) != 3 {
+			continue
+		}
+		latitude := int32(math.Round(reading.Values[0] * 1e7))
+		longitude := int32(math.Round(reading.Values[1] * 1e7))
+		altitude := int32(math.Round(reading.Values[2]))
+		return &proto.Position{
+			LatitudeI:  &latitude,
+			LongitudeI: &longitude,
+			Altitude:   &altitude,
+			Time:       uint32(at.Unix()),
+		}, true
+	}
+	return nil, false
+}
+
+// PacketTelemetry returns telemetry carried by a TELEMETRY_APP or CAYENNE_APP packet.
+// It returns nil for other packets.
+func PacketTelemetry(packet *proto.MeshPacket) ([]*proto.Telemetry, error) {
+	decoded := packet.GetDecoded()
+	switch decoded.GetPortnum() {
+	case proto.PortNum_TELEMETRY_APP:
+		telemetry := new(proto.Telemetry)
+		if err := protobuf.Unmarshal(decoded.GetPayload(), telemetry); err != nil {
+			return nil, meshtastic.ErrInvalidPacketFormat
+		}
+		return []*proto.Telemetry{telemetry}, nil
+	case proto.PortNum_CAYENNE_APP:
+		readings, err := Decode(decoded.GetPayload())
+		if len(readings) == 0 && err != nil {
+			return nil, err
+		}
+		at := time.Now()
+		if packet.GetRxTime() != 0 {
+			at = time.Unix(int64(packet.GetRxTime()), 0)
+		}
+		return ToTelemetry(readings, at), nil
+	default:
+		return nil, nil
+	}
+}
+
+// TelemetryReceiver receives telemetry of Meshtastic and LPP sensors as a single stream.
+type TelemetryReceiver struct {
+	Receiver meshtastic.PacketReceiver
+
+	pending []TelemetrySample
+}
+
+// TelemetrySample is a telemetry message of a node.
+type TelemetrySample struct {
+	From      uint32
+	Telemetry *proto.Telemetry
+}
+
+// ReceiveTelemetry blocks until the next telemetry message is received.
+func (r *TelemetryReceiver) ReceiveTelemetry(ctx context.Context) (TelemetrySample, error) {
+	for len(r.pending) == 0 {
+		packet, err := r.Receiver.ReceiveFromMesh(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return TelemetrySample{}, err
+		}
+
+		telemetry, err := PacketTelemetry(packet)
+		if err != nil {
+			Logger.Debug("Invalid telemetry", "from", meshtastic.FormatNodeID(packet.GetFrom()), "error", err)
+			continue
+		}
+		for _, message := range telemetry {
+			r.pending = append(r.pending, TelemetrySample{From: packet.GetFrom(), Telemetry: message})
+		}
+	}
+
+	sample := r.pending[0]
+	r.pending = r.pending[1:]
+	return sample, nil
+}
+
+func setOnce[T any](field **T, value T) {
+	if *field == nil {
+		*field = &value
+	}
+}
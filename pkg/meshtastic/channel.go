@@ -0,0 +1,47 @@
+package meshtastic
+
+import (
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// ChannelName returns the name of the channel as it is seen on the mesh and on MQTT.
+// Channels without an explicit name are named after the modem preset, passed as defaultName.
+func ChannelName(settings *proto.ChannelSettings, defaultName string) string {
+	if name := settings.GetName(); name != "" {
+		return name
+	}
+	return defaultName
+}
+
+// ChannelHash computes the one-byte channel hash which is sent on air instead of the channel
+// index in encrypted packets.
+func ChannelHash(name string, psk []byte) uint32 {
+	var hash byte
+	for _, b := range []byte(name) {
+		hash ^= b
+	}
+	for _, b := range ExpandPSK(psk) {
+		hash ^= b
+	}
+	return uint32(hash)
+}
+
+// FindChannel looks up an enabled channel by its index.
+func FindChannel(channels []*proto.Channel, index uint32) (*proto.Channel, bool) {
+	for _, ch := range channels {
+		if ch.GetRole() != proto.Channel_DISABLED && uint32(ch.GetIndex()) == index {
+			return ch, true
+		}
+	}
+	return nil, false
+}
+
+// FindChannelByName looks up an enabled channel by its name.
+func FindChannelByName(channels []*proto.Channel, name, defaultName string) (*proto.Channel, bool) {
+	for _, ch := range channels {
+		if ch.GetRole() != proto.Channel_DISABLED && ChannelName(ch.GetSettings(), defaultName) == name {
+			return ch, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,68 @@
+package meshtastic
+
+import (
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The radio sends and accepts configuration one section at a time, as Config and ModuleConfig
+// messages with a single variant set. LocalConfig and LocalModuleConfig hold all sections at once,
+// in fields named after the variants.
+
+// MergeConfig stores a config section into the matching field of the local config.
+// It returns false if the local config has no such section.
+func MergeConfig(local *proto.LocalConfig, section *proto.Config) bool {
+	return mergeSection(local.ProtoReflect(), section.ProtoReflect())
+}
+
+// MergeModuleConfig stores a module config section into the matching field of the local module config.
+// It returns false if the local module config has no such section.
+func MergeModuleConfig(local *proto.LocalModuleConfig, section *proto.ModuleConfig) bool {
+	return mergeSection(local.ProtoReflect(), section.ProtoReflect())
+}
+
+// SplitConfig returns a config section for every section set in the local config.
+func SplitConfig(local *proto.LocalConfig) []*proto.Config {
+	return splitSections(local.ProtoReflect(), func() *proto.Config { return new(proto.Config) })
+}
+
+// SplitModuleConfig returns a module config section for every section set in the local module config.
+func SplitModuleConfig(local *proto.LocalModuleConfig) []*proto.ModuleConfig {
+	return splitSections(local.ProtoReflect(), func() *proto.ModuleConfig { return new(proto.ModuleConfig) })
+}
+
+func mergeSection(local, section protoreflect.Message) bool {
+	oneof := section.Descriptor().Oneofs().Get(0)
+	field := section.WhichOneof(oneof)
+	if field == nil {
+		return false
+	}
+
+	target := local.Descriptor().Fields().ByName(field.Name())
+	if target == nil || target.Message() == nil {
+		return false
+	}
+	local.Set(target, section.Get(field))
+	return true
+}
+
+func splitSections[T protobuf.Message](local protoreflect.Message, newSection func() T) []T {
+	var sections []T
+	fields := local.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.Message() == nil || !local.Has(field) {
+			continue
+		}
+
+		section := newSection()
+		target := section.ProtoReflect().Descriptor().Fields().ByName(field.Name())
+		if target == nil || target.ContainingOneof() == nil {
+			continue
+		}
+		section.ProtoReflect().Set(target, local.Get(field))
+		sections = append(sections, section)
+	}
+	return sections
+}
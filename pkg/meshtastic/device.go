@@ -24,10 +24,15 @@ func NewConfiguredDevice(ctx context.Context, transport HardwareTransport) (*Dev
 type Device struct {
 	Transport HardwareTransport
 	NodeID    uint32
+	// Notifications receives client notifications read by ReceiveFromMesh. If nil, security
+	// warnings are logged with Logger.
+	Notifications *Notifications
 
 	lastPacketID uint32
 }
 
+var _ NotificationReceiver = &Device{}
+
 // SendToMesh sends a mesh packet over the device's transport.
 // It converts the provided MeshPacket into a ToRadio message with the appropriate payload variant.
 func (d *Device) SendToMesh(ctx context.Context, packet *proto.MeshPacket) error {
@@ -96,7 +101,7 @@ func (d *Device) generatePacketID() uint32 {
 
 // ReceiveFromMesh blocks until a mesh packet is received from the device's transport.
 // It continuously listens for incoming frames and returns the first MeshPacket found.
-// Other packets will be ignored.
+// Client notifications are passed to HandleNotification, other frames will be ignored.
 func (d *Device) ReceiveFromMesh(ctx context.Context) (*proto.MeshPacket, error) {
 	for {
 		frame, err := d.Transport.ReceiveFromRadio(ctx)
@@ -107,7 +112,20 @@ func (d *Device) ReceiveFromMesh(ctx context.Context) (*proto.MeshPacket, error)
 		if packet := frame.GetPacket(); packet != nil {
 			return packet, nil
 		}
+		if notification := frame.GetClientNotification(); notification != nil {
+			d.HandleNotification(notification)
+		}
+	}
+}
+
+// HandleNotification dispatches a client notification received from the device.
+func (d *Device) HandleNotification(notification *proto.ClientNotification) {
+	n := NewNotification(d.NodeID, notification)
+	if d.Notifications == nil {
+		logSecurityNotification(n)
+		return
 	}
+	d.Notifications.Handle(n)
 }
 
 // Config returns a configuration module for the device.
@@ -46,11 +46,22 @@ func (m *DeviceModuleConfig) GetState(ctx context.Context) (DeviceState, error)
 			state.Channels = append(state.Channels, payload.Channel)
 		case *proto.FromRadio_Metadata:
 			state.Device = payload.Metadata
+		case *proto.FromRadio_FileInfo:
+			state.Files = append(state.Files, payload.FileInfo)
 		case *proto.FromRadio_Config:
 			switch payload.Config.PayloadVariant.(type) {
 			case *proto.Config_Network:
 				state.NetworkConfig = payload.Config.GetNetwork()
 			}
+			if state.Config == nil {
+				state.Config = new(proto.LocalConfig)
+			}
+			MergeConfig(state.Config, payload.Config)
+		case *proto.FromRadio_ModuleConfig:
+			if state.ModuleConfig == nil {
+				state.ModuleConfig = new(proto.LocalModuleConfig)
+			}
+			MergeModuleConfig(state.ModuleConfig, payload.ModuleConfig)
 		case *proto.FromRadio_ConfigCompleteId:
 			if payload.ConfigCompleteId == configId {
 				return state, nil
@@ -68,6 +79,9 @@ type DeviceState struct {
 	Channels      []*proto.Channel
 	Device        *proto.DeviceMetadata
 	NetworkConfig *proto.Config_NetworkConfig
+	Config        *proto.LocalConfig
+	ModuleConfig  *proto.LocalModuleConfig
+	Files         []*proto.FileInfo
 }
 
 // CurrentNodeInfo returns the current node info if available.
@@ -0,0 +1,5 @@
+package devicelog
+
+import "github.com/exepirit/meshtastic-go/internal/log"
+
+var Logger log.Logger = new(log.NOOPLogger)
@@ -0,0 +1,111 @@
+package devicelog
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	// DefaultMaxSize is the default size of a log file before it is rotated.
+	DefaultMaxSize = 10 << 20
+	// DefaultMaxBackups is the default number of rotated files kept.
+	DefaultMaxBackups = 5
+)
+
+// RotatingFile is a log file which is renamed once it grows too large. Rotated files get
+// a numeric suffix, path.1 being the most recent one. It is safe for concurrent use,
+// so it can be the output of a slog handler.
+type RotatingFile struct {
+	Path string
+	// MaxSize is the size in bytes after which the file is rotated. Defaults to DefaultMaxSize.
+	MaxSize int64
+	// MaxBackups is the number of rotated files kept. Defaults to DefaultMaxBackups.
+	MaxBackups int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Write appends to the file, rotating it first if the data would exceed the maximum size.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize() {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (f *RotatingFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	backups := f.MaxBackups
+	if backups <= 0 {
+		backups = DefaultMaxBackups
+	}
+	_ = os.Remove(backupName(f.Path, backups))
+	for i := backups - 1; i >= 1; i-- {
+		if err := os.Rename(backupName(f.Path, i), backupName(f.Path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(f.Path, backupName(f.Path, 1)); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func (f *RotatingFile) maxSize() int64 {
+	if f.MaxSize <= 0 {
+		return DefaultMaxSize
+	}
+	return f.MaxSize
+}
+
+func backupName(path string, index int) string {
+	return fmt.Sprintf("%s.%d", path, index)
+}
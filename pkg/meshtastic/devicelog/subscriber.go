@@ -0,0 +1,95 @@
+// Package devicelog streams firmware logs of a node into log/slog.
+//
+// Nodes send their logs to the client when the debug log API is enabled in the security
+// config, see SetDebugLogAPI.
+package devicelog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/admin"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// Record converts a firmware log record of the node into a slog record. The record has
+// a "node" attribute and a "source" attribute with the firmware module, if known.
+func Record(node uint32, record *proto.LogRecord) slog.Record {
+	t := time.Now()
+	if record.GetTime() != 0 {
+		t = time.Unix(int64(record.GetTime()), 0)
+	}
+
+	r := slog.NewRecord(t, meshtastic.LogLevel(record.GetLevel()), record.GetMessage(), 0)
+	r.AddAttrs(slog.String("node", meshtastic.FormatNodeID(node)))
+	if source := record.GetSource(); source != "" {
+		r.AddAttrs(slog.String("source", source))
+	}
+	return r
+}
+
+// Subscriber passes firmware logs of a node to a slog handler.
+//
+// The subscriber reads frames from the transport, so it should own the transport or receive
+// frames through a route matching meshtastic.MatchLogRecords.
+type Subscriber struct {
+	Transport meshtastic.HardwareTransport
+	// Node is the number of the node, used for attribution.
+	Node uint32
+	// Handler receives the records. Defaults to the handler of the default slog logger.
+	Handler slog.Handler
+	// Notifications receives client notifications read from the transport, usually the Device.
+	// If nil, security warnings are logged with meshtastic.Logger.
+	Notifications meshtastic.NotificationReceiver
+}
+
+// Run passes log records to the handler until the context is done or the transport fails.
+func (s *Subscriber) Run(ctx context.Context) error {
+	handler := s.Handler
+	if handler == nil {
+		handler = slog.Default().Handler()
+	}
+
+	for {
+		frame, err := s.Transport.ReceiveFromRadio(ctx)
+		if err != nil {
+			return err
+		}
+		record := frame.GetLogRecord()
+		if record == nil {
+			meshtastic.HandleNotificationFrame(s.Notifications, frame)
+			continue
+		}
+
+		r := Record(s.Node, record)
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err = handler.Handle(ctx, r); err != nil {
+			Logger.Warn("Failed to handle device log record", "error", err)
+		}
+	}
+}
+
+// SetDebugLogAPI enables or disables streaming of firmware logs to clients. The node keeps
+// the setting, so logs are streamed after reconnecting too.
+func SetDebugLogAPI(ctx context.Context, client *admin.Client, enabled bool) error {
+	config, err := client.GetConfig(ctx, proto.AdminMessage_SECURITY_CONFIG)
+	if err != nil {
+		return err
+	}
+	security := config.GetSecurity()
+	if security == nil {
+		return admin.ErrUnexpectedResponse
+	}
+	if security.GetDebugLogApiEnabled() == enabled {
+		return nil
+	}
+
+	security.DebugLogApiEnabled = enabled
+	return client.SetConfig(ctx, &proto.Config{
+		PayloadVariant: &proto.Config_Security{Security: security},
+	})
+}
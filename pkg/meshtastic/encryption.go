@@ -5,6 +5,8 @@ import (
 	"crypto/cipher"
 	"encoding/base64"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
 	protobuf "google.golang.org/protobuf/proto"
 )
@@ -14,10 +16,7 @@ func DecryptPSK(packet *proto.MeshPacket, block cipher.Block) (*proto.Data, erro
 	encrypted := packet.GetEncrypted()
 	decrypted := make([]byte, len(encrypted))
 
-	nonce := make([]byte, 16)
-	binary.LittleEndian.PutUint32(nonce[0:], packet.GetId())
-	binary.LittleEndian.PutUint32(nonce[8:], packet.GetFrom())
-	cipher.NewCTR(block, nonce).XORKeyStream(decrypted, encrypted)
+	cipher.NewCTR(block, packetNonce(packet)).XORKeyStream(decrypted, encrypted)
 
 	decryptedData := new(proto.Data)
 	if err := protobuf.Unmarshal(decrypted, decryptedData); err != nil {
@@ -26,6 +25,27 @@ func DecryptPSK(packet *proto.MeshPacket, block cipher.Block) (*proto.Data, erro
 	return decryptedData, nil
 }
 
+// EncryptPSK encrypts the decoded payload of a MeshPacket using the provided AES cipher block.
+// It returns the encrypted bytes suitable for the MeshPacket_Encrypted payload variant.
+func EncryptPSK(packet *proto.MeshPacket, block cipher.Block) ([]byte, error) {
+	plain, err := protobuf.Marshal(packet.GetDecoded())
+	if err != nil {
+		return nil, fmt.Errorf("marshalling error: %w", err)
+	}
+
+	encrypted := make([]byte, len(plain))
+	cipher.NewCTR(block, packetNonce(packet)).XORKeyStream(encrypted, plain)
+	return encrypted, nil
+}
+
+// packetNonce builds the AES-CTR nonce of the packet from its ID and sender.
+func packetNonce(packet *proto.MeshPacket) []byte {
+	nonce := make([]byte, 16)
+	binary.LittleEndian.PutUint32(nonce[0:], packet.GetId())
+	binary.LittleEndian.PutUint32(nonce[8:], packet.GetFrom())
+	return nonce
+}
+
 // DecodeCipherKeyBase64 converts a base64-encoded string into an AES cipher block.
 func DecodeCipherKeyBase64(key string) (cipher.Block, error) {
 	bytes, err := base64.StdEncoding.DecodeString(key)
@@ -39,3 +59,49 @@ func DecodeCipherKeyBase64(key string) (cipher.Block, error) {
 	}
 	return c, nil
 }
+
+// DefaultPSK is the well-known key used by channels configured with a single-byte PSK index.
+var DefaultPSK = []byte{
+	0xd4, 0xf1, 0xbb, 0x3a, 0x20, 0x29, 0x07, 0x59,
+	0xf0, 0xbc, 0xff, 0xab, 0xcf, 0x4e, 0x69, 0x01,
+}
+
+// ExpandPSK converts the PSK stored in channel settings into an AES key.
+// It returns nil if the channel is not encrypted.
+func ExpandPSK(psk []byte) []byte {
+	switch {
+	case len(psk) == 0:
+		return nil
+	case len(psk) == 1:
+		if psk[0] == 0 {
+			return nil
+		}
+		key := make([]byte, len(DefaultPSK))
+		copy(key, DefaultPSK)
+		key[len(key)-1] += psk[0] - 1
+		return key
+	case len(psk) < 16:
+		key := make([]byte, 16)
+		copy(key, psk)
+		return key
+	case len(psk) > 16 && len(psk) < 32:
+		key := make([]byte, 32)
+		copy(key, psk)
+		return key
+	default:
+		return psk
+	}
+}
+
+// NewChannelCipher creates an AES cipher block for a channel PSK. It returns nil block
+// and nil error if the channel is not encrypted.
+func NewChannelCipher(psk []byte) (cipher.Block, error) {
+	key := ExpandPSK(psk)
+	if key == nil {
+		return nil, nil
+	}
+	if len(key) > 32 {
+		return nil, errors.New("PSK is too long")
+	}
+	return aes.NewCipher(key)
+}
@@ -2,7 +2,18 @@ package meshtastic
 
 import (
 	"errors"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
 )
 
 // ErrInvalidPacketFormat indicates a problem in structure of received packet.
 var ErrInvalidPacketFormat = errors.New("invalid packet data format")
+
+// RoutingError is reported by the mesh when a packet could not be delivered or processed.
+type RoutingError struct {
+	Reason proto.Routing_Error
+}
+
+func (e RoutingError) Error() string {
+	return "routing error: " + e.Reason.String()
+}
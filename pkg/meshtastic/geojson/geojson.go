@@ -0,0 +1,63 @@
+// Package geojson provides minimal GeoJSON types used to export mesh data to mapping tools.
+package geojson
+
+// Coordinate scale of integer positions in Meshtastic messages (latitude_i, longitude_i).
+const degreesScale = 1e-7
+
+// FeatureCollection is a GeoJSON feature collection.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// NewFeatureCollection creates a feature collection.
+func NewFeatureCollection(features ...Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// Feature is a GeoJSON feature.
+type Feature struct {
+	Type       string         `json:"type"`
+	ID         any            `json:"id,omitempty"`
+	Geometry   Geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// NewFeature creates a feature with the given geometry and properties.
+func NewFeature(geometry Geometry, properties map[string]any) Feature {
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	return Feature{Type: "Feature", Geometry: geometry, Properties: properties}
+}
+
+// Geometry is a GeoJSON geometry.
+type Geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// Position is a GeoJSON position: longitude, latitude and optional altitude.
+type Position []float64
+
+// PositionI converts Meshtastic integer coordinates (1e-7 degrees) and altitude in meters into a position.
+func PositionI(latitudeI, longitudeI, altitude int32) Position {
+	pos := Position{float64(longitudeI) * degreesScale, float64(latitudeI) * degreesScale}
+	if altitude != 0 {
+		pos = append(pos, float64(altitude))
+	}
+	return pos
+}
+
+// Point creates a point geometry.
+func Point(pos Position) Geometry {
+	return Geometry{Type: "Point", Coordinates: pos}
+}
+
+// LineString creates a line geometry.
+func LineString(positions ...Position) Geometry {
+	return Geometry{Type: "LineString", Coordinates: positions}
+}
@@ -0,0 +1,59 @@
+package iptunnel
+
+import (
+	"net/netip"
+	"sync"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+)
+
+// Prefix is the network of tunnel addresses. The last two bytes of an address are the lowest
+// 16 bits of the node number.
+var Prefix = netip.MustParsePrefix("10.115.0.0/16")
+
+// BroadcastAddress is the tunnel address mapped to the mesh broadcast.
+var BroadcastAddress = netip.AddrFrom4([4]byte{10, 115, 255, 255})
+
+// NodeAddress returns the tunnel address of a node.
+func NodeAddress(node uint32) netip.Addr {
+	return netip.AddrFrom4([4]byte{10, 115, byte(node >> 8), byte(node)})
+}
+
+// AddressMap resolves tunnel addresses to node numbers. Addresses only keep 16 bits of the node
+// number, so nodes must be added to the map before packets can be routed to them.
+// It is safe for concurrent use.
+type AddressMap struct {
+	lock  sync.RWMutex
+	nodes map[uint16]uint32
+}
+
+// Add makes the node reachable by its tunnel address. A node with the same address is replaced.
+func (m *AddressMap) Add(node uint32) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.nodes == nil {
+		m.nodes = make(map[uint16]uint32)
+	}
+	if previous, ok := m.nodes[uint16(node)]; ok && previous != node {
+		Logger.Warn("Tunnel address collision",
+			"address", NodeAddress(node), "old", meshtastic.FormatNodeID(previous), "new", meshtastic.FormatNodeID(node))
+	}
+	m.nodes[uint16(node)] = node
+}
+
+// Resolve returns the node number of the address. Broadcast and multicast addresses
+// resolve to the mesh broadcast.
+func (m *AddressMap) Resolve(addr netip.Addr) (uint32, bool) {
+	if addr == BroadcastAddress || addr.IsMulticast() || addr == netip.AddrFrom4([4]byte{255, 255, 255, 255}) {
+		return meshtastic.BroadcastNodenum, true
+	}
+	if m == nil || !Prefix.Contains(addr) {
+		return 0, false
+	}
+
+	b := addr.As4()
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	node, ok := m.nodes[uint16(b[2])<<8|uint16(b[3])]
+	return node, ok
+}
@@ -0,0 +1,147 @@
+package iptunnel
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// Every fragment starts with a header:
+//
+//	byte 0     version in the high nibble, flags in the low nibble
+//	bytes 1-2  datagram ID, big endian
+//	byte 3     fragment index
+//	byte 4     number of fragments
+const (
+	headerSize    = 5
+	frameVersion  = 1
+	flagDeflated  = 0x01
+	maxFragments  = 255
+	maxFragment   = int(proto.Constants_DATA_PAYLOAD_LEN) - headerSize
+	maxPacketSize = maxFragments * maxFragment
+)
+
+// ErrInvalidFrame is returned for fragments with a malformed header.
+var ErrInvalidFrame = errors.New("invalid tunnel frame")
+
+// fragment splits an IP packet into mesh payloads, compressing it if that makes it shorter.
+func fragment(id uint16, packet []byte, compress bool) ([][]byte, error) {
+	flags := byte(0)
+	if compress {
+		if deflated, err := deflate(packet); err == nil && len(deflated) < len(packet) {
+			packet = deflated
+			flags |= flagDeflated
+		}
+	}
+	if len(packet) > maxPacketSize {
+		return nil, fmt.Errorf("packet of %d bytes is too large", len(packet))
+	}
+
+	count := max((len(packet)+maxFragment-1)/maxFragment, 1)
+	fragments := make([][]byte, 0, count)
+	for i := range count {
+		chunk := packet[i*maxFragment : min((i+1)*maxFragment, len(packet))]
+		frame := make([]byte, headerSize, headerSize+len(chunk))
+		frame[0] = frameVersion<<4 | flags
+		binary.BigEndian.PutUint16(frame[1:3], id)
+		frame[3] = byte(i)
+		frame[4] = byte(count)
+		fragments = append(fragments, append(frame, chunk...))
+	}
+	return fragments, nil
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func inflate(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, int64(maxPacketSize)))
+}
+
+type datagramKey struct {
+	from uint32
+	id   uint16
+}
+
+type partialDatagram struct {
+	flags     byte
+	fragments [][]byte
+	missing   int
+	started   time.Time
+}
+
+// reassembler collects fragments of datagrams until they are complete.
+type reassembler struct {
+	timeout   time.Duration
+	datagrams map[datagramKey]*partialDatagram
+}
+
+// add stores a fragment and returns the IP packet once all its fragments are received.
+func (r *reassembler) add(from uint32, frame []byte, now time.Time) ([]byte, error) {
+	if len(frame) < headerSize || frame[0]>>4 != frameVersion {
+		return nil, ErrInvalidFrame
+	}
+	flags, index, count := frame[0]&0x0f, int(frame[3]), int(frame[4])
+	if count == 0 || index >= count {
+		return nil, ErrInvalidFrame
+	}
+	r.expire(now)
+
+	key := datagramKey{from: from, id: binary.BigEndian.Uint16(frame[1:3])}
+	datagram, ok := r.datagrams[key]
+	if !ok || len(datagram.fragments) != count {
+		datagram = &partialDatagram{
+			flags:     flags,
+			fragments: make([][]byte, count),
+			missing:   count,
+			started:   now,
+		}
+		if r.datagrams == nil {
+			r.datagrams = make(map[datagramKey]*partialDatagram)
+		}
+		r.datagrams[key] = datagram
+	}
+	if datagram.fragments[index] == nil {
+		datagram.fragments[index] = frame[headerSize:]
+		datagram.missing--
+	}
+	if datagram.missing > 0 {
+		return nil, nil
+	}
+
+	delete(r.datagrams, key)
+	packet := bytes.Join(datagram.fragments, nil)
+	if datagram.flags&flagDeflated != 0 {
+		return inflate(packet)
+	}
+	return packet, nil
+}
+
+// expire drops incomplete datagrams older than the timeout.
+func (r *reassembler) expire(now time.Time) {
+	for key, datagram := range r.datagrams {
+		if now.Sub(datagram.started) > r.timeout {
+			delete(r.datagrams, key)
+		}
+	}
+}
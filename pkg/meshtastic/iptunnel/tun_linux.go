@@ -0,0 +1,76 @@
+//go:build linux
+
+package iptunnel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	tunDevice = "/dev/net/tun"
+	tunSetIff = 0x400454ca // TUNSETIFF
+	iffTUN    = 0x0001
+	iffNoPI   = 0x1000
+	ifNameLen = 16
+	ifReqLen  = 40
+)
+
+// TUN is a Linux TUN interface passing raw IP packets.
+type TUN struct {
+	file *os.File
+	name string
+}
+
+// OpenTUN creates or attaches to the TUN interface with the given name. An empty name lets
+// the kernel choose one. Creating interfaces requires the CAP_NET_ADMIN capability.
+//
+// The interface is not configured. Assign it the tunnel address of the local node and
+// bring it up, for example with "ip addr add 10.115.x.y/16 dev <name> && ip link set <name> up".
+func OpenTUN(name string) (*TUN, error) {
+	if len(name) >= ifNameLen {
+		return nil, fmt.Errorf("interface name %q is too long", name)
+	}
+
+	fd, err := syscall.Open(tunDevice, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", tunDevice, err)
+	}
+
+	var req [ifReqLen]byte
+	copy(req[:ifNameLen-1], name)
+	binary.NativeEndian.PutUint16(req[ifNameLen:], iffTUN|iffNoPI)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), tunSetIff, uintptr(unsafe.Pointer(&req[0])))
+	if errno != 0 {
+		_ = syscall.Close(fd)
+		return nil, fmt.Errorf("failed to create TUN interface: %w", errno)
+	}
+
+	// the descriptor is non-blocking, so the file uses the runtime poller and Close unblocks Read
+	name = string(bytes.TrimRight(req[:ifNameLen], "\x00"))
+	return &TUN{file: os.NewFile(uintptr(fd), name), name: name}, nil
+}
+
+// Name returns the interface name.
+func (t *TUN) Name() string {
+	return t.name
+}
+
+// Read reads a single IP packet.
+func (t *TUN) Read(b []byte) (int, error) {
+	return t.file.Read(b)
+}
+
+// Write writes a single IP packet.
+func (t *TUN) Write(b []byte) (int, error) {
+	return t.file.Write(b)
+}
+
+// Close closes the interface. Non-persistent interfaces are removed by the kernel.
+func (t *TUN) Close() error {
+	return t.file.Close()
+}
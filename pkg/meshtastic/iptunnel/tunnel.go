@@ -0,0 +1,149 @@
+// Package iptunnel carries IPv4 packets between network interfaces of mesh nodes on IP_TUNNEL_APP.
+//
+// Nodes get addresses from the 10.115.0.0/16 network built from their node numbers. Packets are
+// compressed and split into fragments which fit into mesh packets, so the format is only
+// understood by other tunnels of this package.
+package iptunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/netip"
+	"sync/atomic"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// DefaultReassemblyTimeout is the time to wait for missing fragments of a packet.
+const DefaultReassemblyTimeout = 30 * time.Second
+
+// Tunnel forwards IP packets between a network interface and the mesh.
+type Tunnel struct {
+	// Interface reads and writes whole IP packets, like a TUN device. Each Read must return
+	// a single packet.
+	Interface io.ReadWriter
+	// Mesh is used to exchange packets with other nodes.
+	Mesh meshtastic.MeshTransport
+	// LocalNode is the node number of the local node.
+	LocalNode uint32
+	// ChannelIndex is the channel used for tunnel packets.
+	ChannelIndex uint32
+	// Addresses resolves destination addresses. Senders of received packets are added to it.
+	Addresses *AddressMap
+	// Compress enables compression of packets before fragmentation.
+	Compress bool
+	// ReassemblyTimeout defaults to DefaultReassemblyTimeout.
+	ReassemblyTimeout time.Duration
+
+	nextID atomic.Uint32
+}
+
+// Run forwards packets until the context is cancelled or reading from the interface or the mesh
+// fails. Close the interface after Run returns to release the blocked interface read.
+func (t *Tunnel) Run(ctx context.Context) error {
+	if t.Addresses == nil {
+		t.Addresses = new(AddressMap)
+	}
+	t.nextID.Store(rand.Uint32())
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the uplink may stay blocked in an interface read, so only the first result is awaited
+	errs := make(chan error, 2)
+	go func() { errs <- t.runUplink(ctx) }()
+	go func() { errs <- t.runDownlink(ctx) }()
+	return <-errs
+}
+
+// runUplink sends packets read from the interface to the mesh.
+func (t *Tunnel) runUplink(ctx context.Context) error {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, err := t.Interface.Read(buf)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read from interface: %w", err)
+		}
+		if err = t.Send(ctx, buf[:n]); err != nil {
+			Logger.Debug("IP packet is not sent", "error", err)
+		}
+	}
+}
+
+// Send sends an IPv4 packet to the node owning its destination address.
+func (t *Tunnel) Send(ctx context.Context, packet []byte) error {
+	if len(packet) < 20 || packet[0]>>4 != 4 {
+		return errors.New("not an IPv4 packet")
+	}
+	destAddr := netip.AddrFrom4([4]byte(packet[16:20]))
+	dest, ok := t.Addresses.Resolve(destAddr)
+	if !ok {
+		return fmt.Errorf("no node for address %s", destAddr)
+	}
+
+	fragments, err := fragment(uint16(t.nextID.Add(1)), packet, t.Compress)
+	if err != nil {
+		return err
+	}
+	for _, payload := range fragments {
+		err = t.Mesh.SendToMesh(ctx, &proto.MeshPacket{
+			To:      dest,
+			Channel: t.ChannelIndex,
+			PayloadVariant: &proto.MeshPacket_Decoded{
+				Decoded: &proto.Data{
+					Portnum: proto.PortNum_IP_TUNNEL_APP,
+					Payload: payload,
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send tunnel packet: %w", err)
+		}
+	}
+	return nil
+}
+
+// runDownlink writes packets received from the mesh to the interface.
+func (t *Tunnel) runDownlink(ctx context.Context) error {
+	timeout := t.ReassemblyTimeout
+	if timeout <= 0 {
+		timeout = DefaultReassemblyTimeout
+	}
+	fragments := &reassembler{timeout: timeout}
+
+	for {
+		packet, err := t.Mesh.ReceiveFromMesh(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		decoded := packet.GetDecoded()
+		if decoded.GetPortnum() != proto.PortNum_IP_TUNNEL_APP || packet.GetFrom() == t.LocalNode {
+			continue
+		}
+		ipPacket, err := fragments.add(packet.GetFrom(), decoded.GetPayload(), time.Now())
+		if err != nil {
+			Logger.Debug("Invalid tunnel packet", "from", meshtastic.FormatNodeID(packet.GetFrom()), "error", err)
+			continue
+		}
+		if ipPacket == nil {
+			continue // waiting for more fragments
+		}
+
+		t.Addresses.Add(packet.GetFrom())
+		if _, err = t.Interface.Write(ipPacket); err != nil {
+			return fmt.Errorf("failed to write to interface: %w", err)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package iptunnel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/sim"
+)
+
+const (
+	nodeA = 0x0a0b1234
+	nodeB = 0x0c0d5678
+)
+
+// chanInterface passes packets through channels, like a TUN device.
+type chanInterface struct {
+	in        chan []byte
+	out       chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newChanInterface() *chanInterface {
+	return &chanInterface{in: make(chan []byte, 16), out: make(chan []byte, 16), closed: make(chan struct{})}
+}
+
+func (i *chanInterface) Read(b []byte) (int, error) {
+	select {
+	case packet := <-i.in:
+		return copy(b, packet), nil
+	case <-i.closed:
+		return 0, io.EOF
+	}
+}
+
+func (i *chanInterface) Write(b []byte) (int, error) {
+	i.out <- bytes.Clone(b)
+	return len(b), nil
+}
+
+func (i *chanInterface) Close() {
+	i.closeOnce.Do(func() { close(i.closed) })
+}
+
+func startTunnel(t *testing.T, mesh *sim.Mesh, node uint32, tunnel *Tunnel) *chanInterface {
+	t.Helper()
+	iface := newChanInterface()
+	tunnel.Interface = iface
+	tunnel.Mesh = mesh.Node(node)
+	tunnel.LocalNode = node
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = tunnel.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		iface.Close()
+		<-done
+	})
+	return iface
+}
+
+// ipPacket builds an IPv4 packet between tunnel addresses of the nodes.
+func ipPacket(from, to uint32, payload []byte) []byte {
+	packet := make([]byte, 20, 20+len(payload))
+	packet[0] = 0x45
+	src, dst := NodeAddress(from).As4(), NodeAddress(to).As4()
+	copy(packet[12:16], src[:])
+	copy(packet[16:20], dst[:])
+	return append(packet, payload...)
+}
+
+func receivePacket(t *testing.T, iface *chanInterface, timeout time.Duration) []byte {
+	t.Helper()
+	select {
+	case packet := <-iface.out:
+		return packet
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+func TestTunnelFragmented(t *testing.T) {
+	random := make([]byte, 1000)
+	rand.New(rand.NewSource(1)).Read(random)
+	tests := []struct {
+		name     string
+		compress bool
+		payload  []byte
+	}{
+		{name: "plain", payload: random},
+		{name: "deflated", compress: true, payload: bytes.Repeat([]byte("tunnel "), 150)},
+		{name: "incompressible", compress: true, payload: random},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mesh := new(sim.Mesh)
+			sender := &Tunnel{Addresses: new(AddressMap), Compress: tt.compress}
+			sender.Addresses.Add(nodeB)
+			receiver := &Tunnel{Compress: tt.compress}
+			senderIface := startTunnel(t, mesh, nodeA, sender)
+			receiverIface := startTunnel(t, mesh, nodeB, receiver)
+
+			packet := ipPacket(nodeA, nodeB, tt.payload)
+			if len(packet) <= maxFragment {
+				t.Fatalf("packet of %d bytes fits into a fragment", len(packet))
+			}
+			senderIface.in <- packet
+
+			got := receivePacket(t, receiverIface, time.Second)
+			if !bytes.Equal(got, packet) {
+				t.Fatalf("received %d bytes, want the sent %d bytes", len(got), len(packet))
+			}
+			// the sender becomes reachable for replies
+			if node, ok := receiver.Addresses.Resolve(NodeAddress(nodeA)); !ok || node != nodeA {
+				t.Errorf("sender resolved to %s (%v)", meshtastic.FormatNodeID(node), ok)
+			}
+		})
+	}
+}
+
+func TestFragmentDeflate(t *testing.T) {
+	packet := ipPacket(nodeA, nodeB, bytes.Repeat([]byte("tunnel "), 150))
+	plain, err := fragment(1, packet, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deflated, err := fragment(1, packet, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deflated) >= len(plain) || deflated[0][0]&flagDeflated == 0 {
+		t.Errorf("compression sent %d fragments, uncompressed %d", len(deflated), len(plain))
+	}
+	for _, frame := range plain {
+		if len(frame) > maxFragment+headerSize || frame[0]&flagDeflated != 0 {
+			t.Errorf("invalid uncompressed fragment header %x, %d bytes", frame[:headerSize], len(frame))
+		}
+	}
+}
+
+func TestTunnelLoss(t *testing.T) {
+	mesh := &sim.Mesh{Loss: 0.3}
+	sender := &Tunnel{Addresses: new(AddressMap)}
+	sender.Addresses.Add(nodeB)
+	receiver := &Tunnel{ReassemblyTimeout: 50 * time.Millisecond}
+	senderIface := startTunnel(t, mesh, nodeA, sender)
+	receiverIface := startTunnel(t, mesh, nodeB, receiver)
+
+	rng := rand.New(rand.NewSource(2))
+	sent := make(map[string]bool)
+	for range 10 {
+		payload := make([]byte, 600)
+		rng.Read(payload)
+		packet := ipPacket(nodeA, nodeB, payload)
+		sent[string(packet)] = true
+		senderIface.in <- packet
+	}
+
+	// only complete packets are delivered, packets with lost fragments are dropped
+	received := 0
+	for {
+		packet := receivePacket(t, receiverIface, 200*time.Millisecond)
+		if packet == nil {
+			break
+		}
+		if !sent[string(packet)] {
+			t.Fatalf("received a corrupted packet of %d bytes", len(packet))
+		}
+		received++
+	}
+	if received == len(sent) {
+		t.Logf("all %d packets arrived despite the loss", received)
+	}
+}
+
+func TestReassemblerExpire(t *testing.T) {
+	packet := ipPacket(nodeA, nodeB, make([]byte, 600))
+	first, err := fragment(1, packet, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := fragment(2, packet, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &reassembler{timeout: time.Second}
+	start := time.Now()
+	if got, err := r.add(nodeA, first[0], start); got != nil || err != nil {
+		t.Fatalf("incomplete packet returned %v, %v", got, err)
+	}
+
+	// the incomplete packet expires once the timeout passed
+	later := start.Add(2 * time.Second)
+	if _, err = r.add(nodeA, second[0], later); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.datagrams[datagramKey{from: nodeA, id: 1}]; ok {
+		t.Fatal("expired packet is still kept")
+	}
+	for _, frame := range first[1:] {
+		if got, _ := r.add(nodeA, frame, later); got != nil {
+			t.Fatal("packet completed with fragments of an expired one")
+		}
+	}
+
+	for _, frame := range second[1:] {
+		if got, err := r.add(nodeA, frame, later); err != nil || (got != nil && !bytes.Equal(got, packet)) {
+			t.Fatalf("reassembled %d bytes (%v), want %d", len(got), err, len(packet))
+		}
+	}
+	if _, ok := r.datagrams[datagramKey{from: nodeA, id: 2}]; ok {
+		t.Error("complete packet is still kept")
+	}
+}
+
+func TestReassemblerInvalidFrame(t *testing.T) {
+	r := new(reassembler)
+	for _, frame := range [][]byte{
+		{0x10, 0, 1, 0},    // too short
+		{0x20, 0, 1, 0, 1}, // unknown version
+		{0x10, 0, 1, 0, 0}, // no fragments
+		{0x10, 0, 1, 2, 2}, // index out of range
+	} {
+		if _, err := r.add(nodeA, frame, time.Now()); !errors.Is(err, ErrInvalidFrame) {
+			t.Errorf("frame %x: got error %v, want ErrInvalidFrame", frame, err)
+		}
+	}
+}
+
+func TestAddressMap(t *testing.T) {
+	var m AddressMap
+	m.Add(0x00011234)
+	if node, ok := m.Resolve(NodeAddress(0x00011234)); !ok || node != 0x00011234 {
+		t.Errorf("resolved %x (%v), want 00011234", node, ok)
+	}
+
+	// nodes sharing the low 16 bits share an address, the last one added wins
+	m.Add(0x00021234)
+	if node, ok := m.Resolve(NodeAddress(0x00011234)); !ok || node != 0x00021234 {
+		t.Errorf("resolved %x (%v) after collision, want 00021234", node, ok)
+	}
+
+	for _, addr := range []netip.Addr{BroadcastAddress, netip.MustParseAddr("224.0.0.251")} {
+		if node, ok := m.Resolve(addr); !ok || node != meshtastic.BroadcastNodenum {
+			t.Errorf("%s resolved to %x (%v), want broadcast", addr, node, ok)
+		}
+	}
+	for _, addr := range []netip.Addr{NodeAddress(0x5678), netip.MustParseAddr("192.168.1.1")} {
+		if _, ok := m.Resolve(addr); ok {
+			t.Errorf("unknown address %s resolved", addr)
+		}
+	}
+}
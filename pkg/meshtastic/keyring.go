@@ -0,0 +1,132 @@
+package meshtastic
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// ErrNoMatchingKey is returned when none of the keys in a keyring decrypts a packet.
+var ErrNoMatchingKey = errors.New("no matching channel key")
+
+// ChannelKey is a channel name and PSK pair stored in a Keyring.
+type ChannelKey struct {
+	// Name is the channel name, as used in MQTT topics and envelopes.
+	Name string
+	// PSK is the channel PSK as stored in channel settings.
+	PSK []byte
+
+	hash      uint32
+	block     cipher.Block
+	decrypted atomic.Uint64
+}
+
+// Hash returns the channel hash of the key.
+func (k *ChannelKey) Hash() uint32 {
+	return k.hash
+}
+
+// Decrypted returns the number of packets decrypted with the key.
+func (k *ChannelKey) Decrypted() uint64 {
+	return k.decrypted.Load()
+}
+
+// Keyring holds keys of several channels and finds the right one for an encrypted packet.
+// It is safe for concurrent use.
+type Keyring struct {
+	lock sync.RWMutex
+	keys []*ChannelKey
+}
+
+// Add adds a channel key to the keyring.
+func (k *Keyring) Add(name string, psk []byte) (*ChannelKey, error) {
+	block, err := NewChannelCipher(psk)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key of channel %q: %w", name, err)
+	}
+	if block == nil {
+		return nil, fmt.Errorf("channel %q is not encrypted", name)
+	}
+
+	key := &ChannelKey{
+		Name:  name,
+		PSK:   psk,
+		hash:  ChannelHash(name, psk),
+		block: block,
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.keys = append(k.keys, key)
+	return key, nil
+}
+
+// AddBase64 adds a channel key given as a base64-encoded PSK, as shown in the apps.
+func (k *Keyring) AddBase64(name, psk string) (*ChannelKey, error) {
+	bytes, err := base64.StdEncoding.DecodeString(psk)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key of channel %q: %w", name, err)
+	}
+	return k.Add(name, bytes)
+}
+
+// AddChannels adds keys of all encrypted channels from a device channel table.
+func (k *Keyring) AddChannels(channels []*proto.Channel, defaultName string) error {
+	for _, ch := range channels {
+		if ch.GetRole() == proto.Channel_DISABLED || ExpandPSK(ch.GetSettings().GetPsk()) == nil {
+			continue
+		}
+		if _, err := k.Add(ChannelName(ch.GetSettings(), defaultName), ch.GetSettings().GetPsk()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Keys returns all keys in the keyring.
+func (k *Keyring) Keys() []*ChannelKey {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+	return append([]*ChannelKey(nil), k.keys...)
+}
+
+// Candidates returns keys that may decrypt a packet with the given channel hash, published to
+// the given channel. Keys whose name matches the channel come first.
+func (k *Keyring) Candidates(channelID string, hash uint32) []*ChannelKey {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	var named, hashed []*ChannelKey
+	for _, key := range k.keys {
+		switch {
+		case key.Name == channelID:
+			named = append(named, key)
+		case key.hash == hash:
+			hashed = append(hashed, key)
+		}
+	}
+	return append(named, hashed...)
+}
+
+// Decrypt tries candidate keys on an encrypted packet and returns its data along with
+// the key which decrypted it.
+func (k *Keyring) Decrypt(packet *proto.MeshPacket, channelID string) (*proto.Data, *ChannelKey, error) {
+	if packet.GetEncrypted() == nil {
+		return nil, nil, errors.New("packet is not encrypted")
+	}
+
+	for _, key := range k.Candidates(channelID, packet.GetChannel()) {
+		data, err := DecryptPSK(packet, key.block)
+		if err != nil || data.GetPortnum() == proto.PortNum_UNKNOWN_APP {
+			continue // wrong key produces garbage, which rarely parses as valid data
+		}
+		key.decrypted.Add(1)
+		return data, key, nil
+	}
+	return nil, nil, ErrNoMatchingKey
+}
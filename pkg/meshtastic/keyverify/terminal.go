@@ -0,0 +1,87 @@
+package keyverify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TerminalPrompter asks the operator over a text terminal.
+//
+// Reads from In are not interrupted when the context is done.
+type TerminalPrompter struct {
+	In  io.Reader
+	Out io.Writer
+
+	reader *bufio.Reader
+}
+
+var _ Prompter = &TerminalPrompter{}
+
+// ShowNumber prints the security number.
+func (p *TerminalPrompter) ShowNumber(_ context.Context, remote string, number uint32) error {
+	_, err := fmt.Fprintf(p.Out, "Key verification requested by %s. Security number: %s\n", remote, FormatNumber(number))
+	return err
+}
+
+// RequestNumber reads the security number, asking again until a number is entered.
+func (p *TerminalPrompter) RequestNumber(_ context.Context, remote string) (uint32, error) {
+	for {
+		line, err := p.prompt(fmt.Sprintf("Enter the security number shown on %s: ", remote))
+		if err != nil {
+			return 0, err
+		}
+		number, err := ParseNumber(line)
+		if err == nil {
+			return number, nil
+		}
+		if _, err = fmt.Fprintln(p.Out, "Not a security number."); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Confirm prints the verification characters and reads a yes or no answer.
+func (p *TerminalPrompter) Confirm(_ context.Context, remote string, characters string) (bool, error) {
+	line, err := p.prompt(fmt.Sprintf("Verification characters: %s\nDoes %s show the same characters? [y/N]: ", characters, remote))
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(line) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (p *TerminalPrompter) prompt(text string) (string, error) {
+	if p.reader == nil {
+		p.reader = bufio.NewReader(p.In)
+	}
+	if _, err := io.WriteString(p.Out, text); err != nil {
+		return "", err
+	}
+	line, err := p.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// FormatNumber formats a security number in two groups of three digits, as nodes display it.
+func FormatNumber(number uint32) string {
+	return fmt.Sprintf("%03d %03d", number/1000, number%1000)
+}
+
+// ParseNumber parses a security number, ignoring spaces between digits.
+func ParseNumber(text string) (uint32, error) {
+	number, err := strconv.ParseUint(strings.ReplaceAll(text, " ", ""), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(number), nil
+}
@@ -0,0 +1,200 @@
+// Package keyverify drives out-of-band verification of node public keys, carried
+// on KEY_VERIFICATION_APP.
+//
+// Verification runs between two nodes. The initiating operator enters the security number
+// shown to the remote operator, then both operators compare the verification characters
+// shown to them and mark the contact verified. The nodes do the cryptography, the client
+// only relays numbers and decisions between the local node and its operator.
+package keyverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/admin"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// DefaultTimeout is the default time for completing a verification, including the time
+// the operators need to compare numbers.
+const DefaultTimeout = 5 * time.Minute
+
+// ErrRejected is returned when the operator doesn't confirm the verification characters.
+var ErrRejected = errors.New("key verification rejected")
+
+// Prompter exchanges verification data with the operator.
+type Prompter interface {
+	// ShowNumber shows the security number generated by the local node. The operator
+	// of the remote node has to enter it.
+	ShowNumber(ctx context.Context, remote string, number uint32) error
+	// RequestNumber asks for the security number shown to the operator of the remote node.
+	RequestNumber(ctx context.Context, remote string) (uint32, error)
+	// Confirm shows the verification characters and asks whether the remote node shows
+	// the same ones.
+	Confirm(ctx context.Context, remote string, characters string) (bool, error)
+}
+
+// Verifier runs key verification through the local node.
+//
+// The verifier reads client notifications from the device's transport, so it should own
+// the transport or receive frames through a route matching meshtastic.MatchClientNotifications.
+type Verifier struct {
+	// Device is the local device.
+	Device *meshtastic.Device
+	// Prompter interacts with the operator.
+	Prompter Prompter
+	// Timeout is the time to complete a verification started by Verify.
+	// Defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// Verify verifies the key of the remote node and marks it verified. It returns ErrRejected
+// if the operator rejects the verification characters.
+func (v *Verifier) Verify(ctx context.Context, node uint32) error {
+	timeout := v.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := v.send(ctx, &proto.KeyVerificationAdmin{
+		MessageType:   proto.KeyVerificationAdmin_INITIATE_VERIFICATION,
+		RemoteNodenum: node,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start key verification: %w", err)
+	}
+
+	var nonce uint64
+	for {
+		notification, err := v.next(ctx)
+		if err != nil {
+			return fmt.Errorf("key verification with %s failed: %w", meshtastic.FormatNodeID(node), err)
+		}
+
+		if request := notification.GetKeyVerificationNumberRequest(); request != nil && nonce == 0 {
+			nonce = request.GetNonce()
+			number, err := v.Prompter.RequestNumber(ctx, request.GetRemoteLongname())
+			if err != nil {
+				v.cancel(node, nonce)
+				return err
+			}
+			err = v.send(ctx, &proto.KeyVerificationAdmin{
+				MessageType:    proto.KeyVerificationAdmin_PROVIDE_SECURITY_NUMBER,
+				RemoteNodenum:  node,
+				Nonce:          nonce,
+				SecurityNumber: &number,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to send security number: %w", err)
+			}
+		}
+
+		if final := notification.GetKeyVerificationFinal(); final != nil && nonce != 0 && final.GetNonce() == nonce {
+			return v.confirm(ctx, node, final)
+		}
+	}
+}
+
+// Serve handles verifications started by remote nodes until the context is done. It shows
+// the security number to the local operator and confirms the verification characters.
+func (v *Verifier) Serve(ctx context.Context) error {
+	for {
+		notification, err := v.next(ctx)
+		if err != nil {
+			return err
+		}
+
+		if inform := notification.GetKeyVerificationNumberInform(); inform != nil {
+			err = v.Prompter.ShowNumber(ctx, inform.GetRemoteLongname(), inform.GetSecurityNumber())
+			if err != nil {
+				Logger.Warn("Failed to show security number", "remote", inform.GetRemoteLongname(), "error", err)
+			}
+		}
+
+		if final := notification.GetKeyVerificationFinal(); final != nil && !final.GetIsSender() {
+			err = v.confirm(ctx, 0, final)
+			switch {
+			case err == nil:
+				Logger.Info("Key verified", "remote", final.GetRemoteLongname())
+			case errors.Is(err, ErrRejected):
+				Logger.Warn("Key verification rejected", "remote", final.GetRemoteLongname())
+			default:
+				Logger.Error("Key verification failed", "remote", final.GetRemoteLongname(), "error", err)
+			}
+		}
+	}
+}
+
+// confirm asks the operator to compare the verification characters and reports the decision
+// to the node. The node of a verification started remotely is not known and left zero,
+// the local node identifies the verification by its nonce.
+func (v *Verifier) confirm(ctx context.Context, node uint32, final *proto.KeyVerificationFinal) error {
+	ok, err := v.Prompter.Confirm(ctx, final.GetRemoteLongname(), final.GetVerificationCharacters())
+	if err != nil {
+		v.cancel(node, final.GetNonce())
+		return err
+	}
+
+	messageType := proto.KeyVerificationAdmin_DO_VERIFY
+	if !ok {
+		messageType = proto.KeyVerificationAdmin_DO_NOT_VERIFY
+	}
+	err = v.send(ctx, &proto.KeyVerificationAdmin{
+		MessageType:   messageType,
+		RemoteNodenum: node,
+		Nonce:         final.GetNonce(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send verification result: %w", err)
+	}
+	if !ok {
+		return ErrRejected
+	}
+	return nil
+}
+
+// cancel aborts the verification on the node. It is used when the operator can't be asked,
+// so it works even if the context is done.
+func (v *Verifier) cancel(node uint32, nonce uint64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := v.send(ctx, &proto.KeyVerificationAdmin{
+		MessageType:   proto.KeyVerificationAdmin_DO_NOT_VERIFY,
+		RemoteNodenum: node,
+		Nonce:         nonce,
+	})
+	if err != nil {
+		Logger.Warn("Failed to cancel key verification", "error", err)
+	}
+}
+
+func (v *Verifier) send(ctx context.Context, verification *proto.KeyVerificationAdmin) error {
+	client := &admin.Client{Device: v.Device}
+	return client.KeyVerification(ctx, verification)
+}
+
+// next returns the next client notification about key verification.
+func (v *Verifier) next(ctx context.Context) (*proto.ClientNotification, error) {
+	for {
+		frame, err := v.Device.Transport.ReceiveFromRadio(ctx)
+		if err != nil {
+			return nil, err
+		}
+		notification := frame.GetClientNotification()
+		switch notification.GetPayloadVariant().(type) {
+		case *proto.ClientNotification_KeyVerificationNumberInform,
+			*proto.ClientNotification_KeyVerificationNumberRequest,
+			*proto.ClientNotification_KeyVerificationFinal:
+			return notification, nil
+		}
+		if notification != nil {
+			v.Device.HandleNotification(notification)
+		}
+	}
+}
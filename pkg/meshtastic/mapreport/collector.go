@@ -0,0 +1,144 @@
+package mapreport
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/geojson"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/mqtt"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// NodeReport is the latest map report of a node.
+type NodeReport struct {
+	NodeNum   uint32
+	GatewayID string
+	Report    *proto.MapReport
+	Received  time.Time
+}
+
+// Collector aggregates map reports of other nodes into a node map.
+// It is safe for concurrent use.
+type Collector struct {
+	// MaxAge is the time after which a node without new reports is removed from the map.
+	// Zero means nodes are kept forever.
+	MaxAge time.Duration
+
+	lock  sync.RWMutex
+	nodes map[uint32]NodeReport
+}
+
+// Run collects reports from envelopes received from the broker until the context is cancelled
+// or the receiver fails. Subscribe the transport to the TopicMap subtree to receive them.
+func (c *Collector) Run(ctx context.Context, receiver mqtt.EnvelopeReceiver) error {
+	for {
+		envelope, err := receiver.ReceiveEnvelope(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		c.Handle(envelope)
+	}
+}
+
+// Handle stores the map report carried by the envelope. It returns false if the envelope
+// contains no map report.
+func (c *Collector) Handle(envelope *proto.ServiceEnvelope) bool {
+	packet := envelope.GetPacket()
+	decoded := packet.GetDecoded()
+	if decoded.GetPortnum() != proto.PortNum_MAP_REPORT_APP {
+		return false
+	}
+
+	report := new(proto.MapReport)
+	if err := protobuf.Unmarshal(decoded.GetPayload(), report); err != nil {
+		Logger.Debug("Invalid map report", "from", meshtastic.FormatNodeID(packet.GetFrom()))
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.nodes == nil {
+		c.nodes = make(map[uint32]NodeReport)
+	}
+	c.nodes[packet.GetFrom()] = NodeReport{
+		NodeNum:   packet.GetFrom(),
+		GatewayID: envelope.GetGatewayId(),
+		Report:    report,
+		Received:  time.Now(),
+	}
+	return true
+}
+
+// Node returns the latest report of a node.
+func (c *Collector) Node(num uint32) (NodeReport, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	node, ok := c.nodes[num]
+	if !ok || c.expired(node) {
+		return NodeReport{}, false
+	}
+	return node, true
+}
+
+// Nodes returns the latest reports of all known nodes, ordered by node number.
+func (c *Collector) Nodes() []NodeReport {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	nodes := make([]NodeReport, 0, len(c.nodes))
+	for num, node := range c.nodes {
+		if c.expired(node) {
+			delete(c.nodes, num)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	slices.SortFunc(nodes, func(a, b NodeReport) int {
+		return cmp.Compare(a.NodeNum, b.NodeNum)
+	})
+	return nodes
+}
+
+// GeoJSON exports nodes which reported their location as a collection of points.
+func (c *Collector) GeoJSON() geojson.FeatureCollection {
+	var features []geojson.Feature
+	for _, node := range c.Nodes() {
+		report := node.Report
+		if !report.GetHasOptedReportLocation() {
+			continue
+		}
+
+		pos := geojson.PositionI(report.GetLatitudeI(), report.GetLongitudeI(), report.GetAltitude())
+		feature := geojson.NewFeature(geojson.Point(pos), map[string]any{
+			"id":                meshtastic.FormatNodeID(node.NodeNum),
+			"longName":          report.GetLongName(),
+			"shortName":         report.GetShortName(),
+			"role":              report.GetRole().String(),
+			"hwModel":           report.GetHwModel().String(),
+			"firmwareVersion":   report.GetFirmwareVersion(),
+			"region":            report.GetRegion().String(),
+			"modemPreset":       report.GetModemPreset().String(),
+			"positionPrecision": report.GetPositionPrecision(),
+			"onlineLocalNodes":  report.GetNumOnlineLocalNodes(),
+			"gatewayId":         node.GatewayID,
+			"lastReport":        node.Received.UTC().Format(time.RFC3339),
+			"hasDefaultChannel": report.GetHasDefaultChannel(),
+		})
+		feature.ID = meshtastic.FormatNodeID(node.NodeNum)
+		features = append(features, feature)
+	}
+	return geojson.NewFeatureCollection(features...)
+}
+
+func (c *Collector) expired(node NodeReport) bool {
+	return c.MaxAge > 0 && time.Since(node.Received) > c.MaxAge
+}
@@ -0,0 +1,111 @@
+// Package mapreport publishes and collects node map reports sent on MAP_REPORT_APP
+// to the "<root>/2/map/" MQTT topic.
+package mapreport
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// DefaultPublishInterval is used when the settings do not specify a publish interval.
+const DefaultPublishInterval = time.Hour
+
+// EnvelopeSender defines the interface for publishing map report envelopes, implemented by mqtt.Transport.
+type EnvelopeSender interface {
+	SendMapEnvelope(envelope *proto.ServiceEnvelope) error
+}
+
+// Publisher periodically publishes a map report of a gateway node.
+type Publisher struct {
+	// Broker is the MQTT transport used for publishing.
+	Broker EnvelopeSender
+	// NodeNum is the node number of the gateway.
+	NodeNum uint32
+	// ChannelID is the name of the gateway's primary channel.
+	ChannelID string
+	// Settings defines the publish interval and position precision.
+	Settings *proto.ModuleConfig_MapReportSettings
+	// Report returns the current state of the node. Position fields are truncated according
+	// to Settings before publishing.
+	Report func() *proto.MapReport
+}
+
+// Run publishes a report immediately and then at the configured interval until the context is cancelled.
+// Failed reports are logged and retried at the next interval.
+func (p *Publisher) Run(ctx context.Context) error {
+	interval := time.Duration(p.Settings.GetPublishIntervalSecs()) * time.Second
+	if interval <= 0 {
+		interval = DefaultPublishInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Publish(); err != nil {
+			Logger.Warn("Failed to publish map report", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Publish publishes the current report once.
+func (p *Publisher) Publish() error {
+	report := protobuf.Clone(p.Report()).(*proto.MapReport)
+	precision := p.Settings.GetPositionPrecision()
+	if !p.Settings.GetShouldReportLocation() || precision == 0 {
+		report.LatitudeI, report.LongitudeI, report.Altitude = 0, 0, 0
+		report.PositionPrecision = 0
+		report.HasOptedReportLocation = false
+	} else {
+		report.LatitudeI = TruncateCoordinate(report.LatitudeI, precision)
+		report.LongitudeI = TruncateCoordinate(report.LongitudeI, precision)
+		report.PositionPrecision = precision
+		report.HasOptedReportLocation = true
+	}
+
+	payload, err := protobuf.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("marshalling error: %w", err)
+	}
+
+	envelope := &proto.ServiceEnvelope{
+		Packet: &proto.MeshPacket{
+			From: p.NodeNum,
+			To:   meshtastic.BroadcastNodenum,
+			PayloadVariant: &proto.MeshPacket_Decoded{
+				Decoded: &proto.Data{
+					Portnum: proto.PortNum_MAP_REPORT_APP,
+					Payload: payload,
+				},
+			},
+		},
+		ChannelId: p.ChannelID,
+		GatewayId: meshtastic.FormatNodeID(p.NodeNum),
+	}
+	if err = p.Broker.SendMapEnvelope(envelope); err != nil {
+		return fmt.Errorf("failed to publish map report: %w", err)
+	}
+	Logger.Debug("Map report published", "precision", report.PositionPrecision)
+	return nil
+}
+
+// TruncateCoordinate keeps only the given number of most significant bits of an integer
+// coordinate and moves it to the center of the resulting area, like firmware does.
+func TruncateCoordinate(value int32, precision uint32) int32 {
+	if precision == 0 || precision >= 32 {
+		return value
+	}
+	truncated := uint32(value) & (^uint32(0) << (32 - precision))
+	truncated += 1 << (31 - precision)
+	return int32(truncated)
+}
@@ -0,0 +1,84 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// EnvelopeReceiver defines the interface for receiving MQTT service envelopes.
+type EnvelopeReceiver interface {
+	// ReceiveEnvelope receives a service envelope from the broker.
+	ReceiveEnvelope(ctx context.Context) (*proto.ServiceEnvelope, error)
+}
+
+var _ EnvelopeReceiver = &Transport{}
+
+// DecryptedEnvelope is an envelope whose packet was decrypted with a key from a keyring.
+type DecryptedEnvelope struct {
+	Envelope *proto.ServiceEnvelope
+	// Key is the key which decrypted the packet. It is nil if the packet arrived decoded.
+	Key *meshtastic.ChannelKey
+}
+
+var _ meshtastic.PacketReceiver = &DecryptingReceiver{}
+
+// DecryptingReceiver receives envelopes and transparently decrypts their packets using a keyring.
+type DecryptingReceiver struct {
+	// Receiver is the source of envelopes, usually a Transport.
+	Receiver EnvelopeReceiver
+	// Keyring holds keys of channels to decrypt.
+	Keyring *meshtastic.Keyring
+	// KeepEncrypted makes the receiver return packets no key matched instead of skipping them.
+	KeepEncrypted bool
+}
+
+// ReceiveDecrypted receives the next envelope and decrypts its packet.
+func (r *DecryptingReceiver) ReceiveDecrypted(ctx context.Context) (DecryptedEnvelope, error) {
+	for {
+		envelope, err := r.Receiver.ReceiveEnvelope(ctx)
+		if err != nil {
+			return DecryptedEnvelope{}, err
+		}
+
+		packet := envelope.GetPacket()
+		if packet.GetEncrypted() == nil {
+			return DecryptedEnvelope{Envelope: envelope}, nil
+		}
+
+		data, key, err := r.Keyring.Decrypt(packet, envelope.GetChannelId())
+		switch {
+		case errors.Is(err, meshtastic.ErrNoMatchingKey):
+			Logger.Debug("No key for packet", "channel", envelope.GetChannelId(), "id", packet.GetId())
+			if r.KeepEncrypted {
+				return DecryptedEnvelope{Envelope: envelope}, nil
+			}
+			continue
+		case err != nil:
+			return DecryptedEnvelope{}, err
+		}
+
+		packet.PayloadVariant = &proto.MeshPacket_Decoded{Decoded: data}
+		return DecryptedEnvelope{Envelope: envelope, Key: key}, nil
+	}
+}
+
+// ReceiveEnvelope receives the next envelope with its packet decrypted.
+func (r *DecryptingReceiver) ReceiveEnvelope(ctx context.Context) (*proto.ServiceEnvelope, error) {
+	decrypted, err := r.ReceiveDecrypted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return decrypted.Envelope, nil
+}
+
+// ReceiveFromMesh receives the next decrypted mesh packet.
+func (r *DecryptingReceiver) ReceiveFromMesh(ctx context.Context) (*proto.MeshPacket, error) {
+	envelope, err := r.ReceiveEnvelope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return envelope.GetPacket(), nil
+}
@@ -0,0 +1,213 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// Gateway bridges a local radio to an MQTT broker through the client API.
+//
+// Packets received by the radio are published to "<RootTopic>/2/e/<channel>/!<gateway>" when uplink
+// is enabled for their channel. Broadcasts published by other gateways are sent by the radio
+// when downlink is enabled for their channel.
+//
+// Unlike downlink by the firmware MQTT module, packets sent through the client API are
+// originated by the radio: the firmware replaces their sender with the radio's node and
+// restarts their hop count. Packets addressed to a specific node are therefore not downlinked,
+// so direct messages of other nodes are not re-sent as the radio's. To relay packets with
+// their original sender, enable the firmware MQTT module instead, with a ClientProxy if the
+// radio has no network connection.
+type Gateway struct {
+	// Radio is the local device.
+	Radio *meshtastic.Device
+	// Broker is a connected MQTT transport.
+	Broker *Transport
+	// Channels is the channel table of the radio, e.g. DeviceState.Channels.
+	Channels []*proto.Channel
+	// DefaultChannelName is the name of the primary channel when it has no explicit name.
+	// Defaults to the LongFast preset name.
+	DefaultChannelName string
+	// PublishDecrypted disables encryption of uplinked packets even on channels with a PSK.
+	PublishDecrypted bool
+}
+
+// Run bridges packets in both directions until the context is cancelled or an error occurs.
+func (g *Gateway) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	run := func(loop func(context.Context) error) {
+		defer wg.Done()
+		if err := loop(ctx); err != nil {
+			errOnce.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+	}
+
+	wg.Add(2)
+	go run(g.uplink)
+	go run(g.downlink)
+	wg.Wait()
+	return firstErr
+}
+
+// GatewayID returns the gateway identifier used in topics and envelopes.
+func (g *Gateway) GatewayID() string {
+	return meshtastic.FormatNodeID(g.Radio.NodeID)
+}
+
+// uplink publishes packets received by the radio.
+func (g *Gateway) uplink(ctx context.Context) error {
+	for {
+		packet, err := g.Radio.ReceiveFromMesh(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to receive packet from radio: %w", err)
+		}
+
+		envelope, ok, err := g.uplinkEnvelope(packet)
+		if err != nil {
+			Logger.Warn("Failed to prepare packet for uplink", "id", packet.GetId(), "error", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if err = g.Broker.SendEnvelope(envelope); err != nil {
+			return fmt.Errorf("failed to publish packet: %w", err)
+		}
+		Logger.Debug("Packet uplinked", "id", packet.GetId(), "channel", envelope.GetChannelId())
+	}
+}
+
+// uplinkEnvelope wraps a packet from the radio into an envelope. It returns false if the packet
+// must not be uplinked.
+func (g *Gateway) uplinkEnvelope(packet *proto.MeshPacket) (*proto.ServiceEnvelope, bool, error) {
+	if packet.GetViaMqtt() {
+		return nil, false, nil // it came from MQTT, do not loop it back
+	}
+
+	channel, ok := meshtastic.FindChannel(g.Channels, packet.GetChannel())
+	if !ok || !channel.GetSettings().GetUplinkEnabled() {
+		return nil, false, nil
+	}
+	name := meshtastic.ChannelName(channel.GetSettings(), g.defaultChannelName())
+
+	block, err := meshtastic.NewChannelCipher(channel.GetSettings().GetPsk())
+	if err != nil {
+		return nil, false, err
+	}
+	if block != nil && !g.PublishDecrypted && packet.GetDecoded() != nil {
+		encrypted, err := meshtastic.EncryptPSK(packet, block)
+		if err != nil {
+			return nil, false, err
+		}
+		packet = &proto.MeshPacket{
+			From:           packet.GetFrom(),
+			To:             packet.GetTo(),
+			Channel:        meshtastic.ChannelHash(name, channel.GetSettings().GetPsk()),
+			Id:             packet.GetId(),
+			RxTime:         packet.GetRxTime(),
+			RxSnr:          packet.GetRxSnr(),
+			RxRssi:         packet.GetRxRssi(),
+			HopLimit:       packet.GetHopLimit(),
+			HopStart:       packet.GetHopStart(),
+			WantAck:        packet.GetWantAck(),
+			Priority:       packet.GetPriority(),
+			RelayNode:      packet.GetRelayNode(),
+			NextHop:        packet.GetNextHop(),
+			PayloadVariant: &proto.MeshPacket_Encrypted{Encrypted: encrypted},
+		}
+	}
+
+	return &proto.ServiceEnvelope{
+		Packet:    packet,
+		ChannelId: name,
+		GatewayId: g.GatewayID(),
+	}, true, nil
+}
+
+// downlink sends broadcasts from the broker through the radio.
+func (g *Gateway) downlink(ctx context.Context) error {
+	for {
+		envelope, err := g.Broker.ReceiveEnvelope(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to receive envelope: %w", err)
+		}
+
+		packet, ok, err := g.downlinkPacket(envelope)
+		if err != nil {
+			Logger.Debug("Failed to prepare packet for downlink", "error", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		err = g.Radio.Transport.SendToRadio(ctx, &proto.ToRadio{
+			PayloadVariant: &proto.ToRadio_Packet{Packet: packet},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send packet to radio: %w", err)
+		}
+		Logger.Debug("Packet downlinked", "id", packet.GetId(), "channel", envelope.GetChannelId())
+	}
+}
+
+// downlinkPacket extracts a packet from an envelope and prepares it for the radio. It returns false
+// if the packet must not be sent.
+func (g *Gateway) downlinkPacket(envelope *proto.ServiceEnvelope) (*proto.MeshPacket, bool, error) {
+	packet := envelope.GetPacket()
+	if packet == nil || envelope.GetGatewayId() == g.GatewayID() || packet.GetFrom() == g.Radio.NodeID {
+		return nil, false, nil // our own uplink echoed by the broker
+	}
+	if packet.GetTo() != meshtastic.BroadcastNodenum {
+		return nil, false, nil // the radio would send it as its own
+	}
+
+	channel, ok := meshtastic.FindChannelByName(g.Channels, envelope.GetChannelId(), g.defaultChannelName())
+	if !ok || !channel.GetSettings().GetDownlinkEnabled() {
+		return nil, false, nil
+	}
+
+	if packet.GetEncrypted() != nil {
+		block, err := meshtastic.NewChannelCipher(channel.GetSettings().GetPsk())
+		if err != nil {
+			return nil, false, err
+		}
+		if block == nil {
+			return nil, false, errors.New("encrypted packet on a channel without PSK")
+		}
+		data, err := meshtastic.DecryptPSK(packet, block)
+		if err != nil {
+			return nil, false, err
+		}
+		packet.PayloadVariant = &proto.MeshPacket_Decoded{Decoded: data}
+	}
+
+	packet.Channel = uint32(channel.GetIndex())
+	packet.ViaMqtt = true
+	return packet, true, nil
+}
+
+func (g *Gateway) defaultChannelName() string {
+	if g.DefaultChannelName != "" {
+		return g.DefaultChannelName
+	}
+	return meshtastic.PresetLongFast.Name
+}
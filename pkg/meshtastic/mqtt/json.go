@@ -0,0 +1,242 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// jsonDownlinkChannel is the only channel name on which firmware accepts JSON downlink messages.
+const jsonDownlinkChannel = "mqtt"
+
+// jsonPacket is a packet serialized to JSON by the firmware MQTT module.
+type jsonPacket struct {
+	ID        uint32          `json:"id"`
+	Timestamp uint32          `json:"timestamp"`
+	To        uint32          `json:"to"`
+	From      uint32          `json:"from"`
+	Channel   uint32          `json:"channel"`
+	Type      string          `json:"type"`
+	Sender    string          `json:"sender"`
+	RSSI      int32           `json:"rssi"`
+	SNR       float32         `json:"snr"`
+	HopStart  uint32          `json:"hop_start"`
+	HopsAway  uint32          `json:"hops_away"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// jsonNodeInfo is the payload of "nodeinfo" JSON packets.
+type jsonNodeInfo struct {
+	ID        string `json:"id"`
+	LongName  string `json:"longname"`
+	ShortName string `json:"shortname"`
+	Hardware  int32  `json:"hardware"`
+	Role      int32  `json:"role"`
+}
+
+// decodeJSONEnvelope converts a JSON uplink message into a service envelope with a decoded packet.
+func decodeJSONEnvelope(topic Topic, payload []byte) (*proto.ServiceEnvelope, error) {
+	var msg jsonPacket
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return nil, meshtastic.ErrInvalidPacketFormat
+	}
+
+	data, err := decodeJSONPayload(msg.Type, msg.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	hopLimit := uint32(0)
+	if msg.HopStart >= msg.HopsAway {
+		hopLimit = msg.HopStart - msg.HopsAway
+	}
+
+	gatewayID := topic.GatewayID
+	if gatewayID == "" {
+		gatewayID = msg.Sender
+	}
+
+	return &proto.ServiceEnvelope{
+		Packet: &proto.MeshPacket{
+			From:     msg.From,
+			To:       msg.To,
+			Channel:  msg.Channel,
+			Id:       msg.ID,
+			RxTime:   msg.Timestamp,
+			RxSnr:    msg.SNR,
+			RxRssi:   msg.RSSI,
+			HopStart: msg.HopStart,
+			HopLimit: hopLimit,
+			PayloadVariant: &proto.MeshPacket_Decoded{
+				Decoded: data,
+			},
+		},
+		ChannelId: topic.ChannelID,
+		GatewayId: gatewayID,
+	}, nil
+}
+
+// decodeJSONPayload converts the payload of a JSON packet into protobuf-encoded application data.
+func decodeJSONPayload(packetType string, payload json.RawMessage) (*proto.Data, error) {
+	var (
+		port proto.PortNum
+		msg  protobuf.Message
+	)
+
+	switch packetType {
+	case "text", "sendtext":
+		var text struct {
+			Text *string `json:"text"`
+		}
+		var plain string
+		switch {
+		case json.Unmarshal(payload, &plain) == nil:
+		case json.Unmarshal(payload, &text) == nil && text.Text != nil:
+			plain = *text.Text
+		default:
+			plain = string(payload) // text which is valid JSON is embedded as is
+		}
+		return &proto.Data{Portnum: proto.PortNum_TEXT_MESSAGE_APP, Payload: []byte(plain)}, nil
+	case "nodeinfo":
+		var info jsonNodeInfo
+		if err := json.Unmarshal(payload, &info); err != nil {
+			return nil, meshtastic.ErrInvalidPacketFormat
+		}
+		port, msg = proto.PortNum_NODEINFO_APP, &proto.User{
+			Id:        info.ID,
+			LongName:  info.LongName,
+			ShortName: info.ShortName,
+			HwModel:   proto.HardwareModel(info.Hardware),
+			Role:      proto.Config_DeviceConfig_Role(info.Role),
+		}
+	case "position", "sendposition":
+		position := new(proto.Position)
+		port, msg = proto.PortNum_POSITION_APP, position
+		if err := unmarshalJSONFields(payload, position); err != nil {
+			return nil, err
+		}
+	case "waypoint":
+		waypoint := new(proto.Waypoint)
+		port, msg = proto.PortNum_WAYPOINT_APP, waypoint
+		if err := unmarshalJSONFields(payload, waypoint); err != nil {
+			return nil, err
+		}
+	case "neighborinfo":
+		info := new(proto.NeighborInfo)
+		port, msg = proto.PortNum_NEIGHBORINFO_APP, info
+		if err := unmarshalJSONFields(payload, info); err != nil {
+			return nil, err
+		}
+	case "traceroute":
+		route := new(proto.RouteDiscovery)
+		port, msg = proto.PortNum_TRACEROUTE_APP, route
+		if err := unmarshalJSONFields(payload, route); err != nil {
+			return nil, err
+		}
+	case "telemetry":
+		telemetry, err := decodeJSONTelemetry(payload)
+		if err != nil {
+			return nil, err
+		}
+		port, msg = proto.PortNum_TELEMETRY_APP, telemetry
+	default:
+		return nil, fmt.Errorf("%w: unsupported JSON packet type %q", meshtastic.ErrInvalidPacketFormat, packetType)
+	}
+
+	encoded, err := protobuf.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling error: %w", err)
+	}
+	return &proto.Data{Portnum: port, Payload: encoded}, nil
+}
+
+// decodeJSONTelemetry converts the flat telemetry JSON produced by firmware into a Telemetry message.
+// The metrics variant is detected by the keys present in the payload.
+func decodeJSONTelemetry(payload json.RawMessage) (*proto.Telemetry, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &keys); err != nil {
+		return nil, meshtastic.ErrInvalidPacketFormat
+	}
+	has := func(names ...string) bool {
+		for _, name := range names {
+			if _, ok := keys[name]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	telemetry := new(proto.Telemetry)
+	switch {
+	case has("battery_level", "channel_utilization", "air_util_tx", "uptime_seconds"):
+		metrics := new(proto.DeviceMetrics)
+		telemetry.Variant = &proto.Telemetry_DeviceMetrics{DeviceMetrics: metrics}
+		return telemetry, unmarshalJSONFields(payload, metrics)
+	case has("ch1_voltage", "ch1_current", "ch2_voltage", "ch2_current", "ch3_voltage", "ch3_current"):
+		metrics := new(proto.PowerMetrics)
+		telemetry.Variant = &proto.Telemetry_PowerMetrics{PowerMetrics: metrics}
+		return telemetry, unmarshalJSONFields(payload, metrics)
+	default:
+		metrics := new(proto.EnvironmentMetrics)
+		telemetry.Variant = &proto.Telemetry_EnvironmentMetrics{EnvironmentMetrics: metrics}
+		return telemetry, unmarshalJSONFields(payload, metrics)
+	}
+}
+
+// unmarshalJSONFields decodes a JSON object whose keys are protobuf field names.
+func unmarshalJSONFields(payload json.RawMessage, msg protobuf.Message) error {
+	opts := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err := opts.Unmarshal(payload, msg); err != nil {
+		return fmt.Errorf("%w: %w", meshtastic.ErrInvalidPacketFormat, err)
+	}
+	return nil
+}
+
+// JSONTextParams holds parameters of a text message sent through the JSON downlink.
+type JSONTextParams struct {
+	// GatewayNodeNum is the node number of the gateway which should transmit the message.
+	// Firmware only accepts downlink messages addressed from itself.
+	GatewayNodeNum uint32
+	// DestNodeNum is the destination node's number. Defaults to broadcast.
+	DestNodeNum uint32
+	// ChannelIndex is the channel index on the gateway to transmit on.
+	ChannelIndex uint32
+	// Text is the message text.
+	Text string
+}
+
+// jsonDownlink is a JSON downlink command understood by firmware.
+type jsonDownlink struct {
+	From    uint32 `json:"from"`
+	To      uint32 `json:"to"`
+	Channel uint32 `json:"channel"`
+	Type    string `json:"type"`
+	Payload any    `json:"payload"`
+}
+
+// SendJSONText publishes a text message using the firmware "sendtext" JSON downlink convention.
+// The gateway must have a channel named "mqtt" with downlink and JSON enabled.
+func (mt *Transport) SendJSONText(params JSONTextParams) error {
+	to := params.DestNodeNum
+	if to == 0 {
+		to = meshtastic.BroadcastNodenum
+	}
+
+	msgData, err := json.Marshal(jsonDownlink{
+		From:    params.GatewayNodeNum,
+		To:      to,
+		Channel: params.ChannelIndex,
+		Type:    "sendtext",
+		Payload: params.Text,
+	})
+	if err != nil {
+		return fmt.Errorf("marshalling error: %w", err)
+	}
+
+	topic := Topic{Kind: TopicJSON, ChannelID: jsonDownlinkChannel}
+	return mt.publish(topic.String(mt.RootTopic), msgData)
+}
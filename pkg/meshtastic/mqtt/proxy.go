@@ -0,0 +1,262 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// ProxyStatus is the state of the broker connection of a ClientProxy.
+type ProxyStatus int
+
+const (
+	ProxyDisconnected ProxyStatus = iota
+	ProxyConnecting
+	ProxyConnected
+)
+
+func (s ProxyStatus) String() string {
+	switch s {
+	case ProxyConnecting:
+		return "connecting"
+	case ProxyConnected:
+		return "connected"
+	default:
+		return "disconnected"
+	}
+}
+
+// MatchClientProxyMessages matches frames carrying MQTT client proxy messages.
+// Use it to route frames to a ClientProxy through a meshtastic.FrameRouter.
+func MatchClientProxyMessages(frame *proto.FromRadio) bool {
+	return frame.GetMqttClientProxyMessage() != nil
+}
+
+// NewClientProxy creates a proxy configured from the radio's MQTT module settings.
+func NewClientProxy(radio meshtastic.HardwareTransport, config *proto.ModuleConfig_MQTTConfig) *ClientProxy {
+	scheme := "tcp"
+	if config.GetTlsEnabled() {
+		scheme = "ssl"
+	}
+	root := config.GetRoot()
+	if root == "" {
+		root = "msh"
+	}
+
+	return &ClientProxy{
+		Radio:     radio,
+		BrokerURL: fmt.Sprintf("%s://%s", scheme, config.GetAddress()),
+		Username:  config.GetUsername(),
+		Password:  config.GetPassword(),
+		RootTopic: root,
+	}
+}
+
+// ClientProxy relays the MQTT connection of a radio through this host. It is used with radios which
+// have "proxy to client" enabled in the MQTT module and no network connection of their own.
+//
+// Messages the radio wants to publish arrive as FromRadio_MqttClientProxyMessage frames and are
+// published to the broker. Messages received from subscribed topics are sent to the radio as
+// ToRadio_MqttClientProxyMessage frames.
+type ClientProxy struct {
+	// Radio is the transport of the proxied device. It should only deliver client proxy frames,
+	// see MatchClientProxyMessages.
+	Radio meshtastic.HardwareTransport
+	// BrokerURL is the URL of the MQTT broker to connect to.
+	BrokerURL string
+	// Username is the username for MQTT authentication.
+	Username string
+	// Password is the password for MQTT authentication.
+	Password string
+	// AppName is a unique identifier for the application, used in the MQTT client ID.
+	AppName string
+	// RootTopic is the base topic for all messages.
+	RootTopic string
+	// Topics are the subscriptions relayed to the radio. Defaults to all encrypted and JSON downlink
+	// topics under RootTopic.
+	Topics []string
+	// ConnectRetryInterval is the delay between attempts of the initial connection. Defaults
+	// to 1 second.
+	ConnectRetryInterval time.Duration
+	// MaxReconnectInterval caps the exponential backoff of reconnection after a lost connection,
+	// which starts with a 1 second delay fixed in the MQTT client. Defaults to 2 minutes.
+	MaxReconnectInterval time.Duration
+	// OnStatus is called whenever the broker connection status changes.
+	OnStatus func(status ProxyStatus)
+	// Notifications receives client notifications read from Radio, usually the Device.
+	// If nil, security warnings are logged with meshtastic.Logger.
+	Notifications meshtastic.NotificationReceiver
+
+	lock   sync.Mutex
+	status ProxyStatus
+	client mqtt.Client
+}
+
+// Status returns the current state of the broker connection.
+func (p *ClientProxy) Status() ProxyStatus {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.status
+}
+
+// Run connects to the broker and relays messages until the context is cancelled or the radio
+// transport fails. The broker connection is re-established automatically.
+func (p *ClientProxy) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p.client = mqtt.NewClient(p.clientOptions(ctx))
+	p.setStatus(ProxyConnecting)
+	p.client.Connect() // connection is retried in background
+	defer func() {
+		p.client.Disconnect(1000)
+		p.setStatus(ProxyDisconnected)
+	}()
+
+	for {
+		frame, err := p.Radio.ReceiveFromRadio(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		message := frame.GetMqttClientProxyMessage()
+		if message == nil {
+			meshtastic.HandleNotificationFrame(p.Notifications, frame)
+			continue
+		}
+		p.publish(message)
+	}
+}
+
+func (p *ClientProxy) clientOptions(ctx context.Context) *mqtt.ClientOptions {
+	randomId := make([]byte, 4)
+	_, _ = rand.Read(randomId)
+
+	retryInterval := p.ConnectRetryInterval
+	if retryInterval <= 0 {
+		retryInterval = time.Second
+	}
+	maxInterval := p.MaxReconnectInterval
+	if maxInterval <= 0 {
+		maxInterval = 2 * time.Minute
+	}
+
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(p.BrokerURL)
+	opts.SetUsername(p.Username)
+	opts.SetPassword(p.Password)
+	opts.SetClientID(fmt.Sprintf("%s-%x", p.AppName, randomId))
+	opts.SetOrderMatters(false)
+	opts.SetKeepAlive(5 * time.Second)
+	opts.SetCleanSession(true)
+	opts.SetAutoReconnect(true)
+	opts.SetConnectRetry(true)
+	opts.SetConnectRetryInterval(retryInterval)
+	opts.SetMaxReconnectInterval(maxInterval)
+	opts.SetOnConnectHandler(func(client mqtt.Client) {
+		if err := p.subscribe(ctx); err != nil {
+			Logger.Error("Failed to create proxy subscriptions", "error", err)
+			return
+		}
+		p.setStatus(ProxyConnected)
+	})
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		Logger.Warn("Proxy connection to broker lost", "error", err)
+		p.setStatus(ProxyDisconnected)
+	})
+	opts.SetReconnectingHandler(func(mqtt.Client, *mqtt.ClientOptions) {
+		p.setStatus(ProxyConnecting)
+	})
+	return opts
+}
+
+// subscribe creates subscriptions whose messages are relayed to the radio.
+func (p *ClientProxy) subscribe(ctx context.Context) error {
+	topics := p.Topics
+	if len(topics) == 0 {
+		topics = []string{p.RootTopic + "/2/e/#", p.RootTopic + "/2/json/mqtt/#"}
+	}
+
+	filters := make(map[string]byte, len(topics))
+	for _, topic := range topics {
+		filters[topic] = 0
+	}
+
+	token := p.client.SubscribeMultiple(filters, func(_ mqtt.Client, message mqtt.Message) {
+		p.relayToRadio(ctx, message)
+	})
+	<-token.Done()
+	return token.Error()
+}
+
+// publish sends a message from the radio to the broker.
+func (p *ClientProxy) publish(message *proto.MqttClientProxyMessage) {
+	if !p.client.IsConnectionOpen() {
+		Logger.Debug("Broker is not connected. Proxied message dropped", "topic", message.GetTopic())
+		return
+	}
+
+	var payload []byte
+	switch data := message.PayloadVariant.(type) {
+	case *proto.MqttClientProxyMessage_Data:
+		payload = data.Data
+	case *proto.MqttClientProxyMessage_Text:
+		payload = []byte(data.Text)
+	}
+
+	token := p.client.Publish(message.GetTopic(), 0, message.GetRetained(), payload)
+	go func() {
+		<-token.Done()
+		if err := token.Error(); err != nil {
+			Logger.Warn("Failed to publish proxied message", "topic", message.GetTopic(), "error", err)
+		}
+	}()
+	Logger.Debug("Proxied message published", "topic", message.GetTopic())
+}
+
+// relayToRadio sends a message from the broker to the radio.
+func (p *ClientProxy) relayToRadio(ctx context.Context, message mqtt.Message) {
+	proxyMessage := &proto.MqttClientProxyMessage{
+		Topic:    message.Topic(),
+		Retained: message.Retained(),
+	}
+	if strings.Contains(message.Topic(), "/json/") {
+		proxyMessage.PayloadVariant = &proto.MqttClientProxyMessage_Text{Text: string(message.Payload())}
+	} else {
+		proxyMessage.PayloadVariant = &proto.MqttClientProxyMessage_Data{Data: message.Payload()}
+	}
+
+	err := p.Radio.SendToRadio(ctx, &proto.ToRadio{
+		PayloadVariant: &proto.ToRadio_MqttClientProxyMessage{MqttClientProxyMessage: proxyMessage},
+	})
+	if err != nil {
+		Logger.Error("Failed to relay message to radio", "topic", message.Topic(), "error", err)
+		return
+	}
+	Logger.Debug("Message relayed to radio", "topic", message.Topic())
+}
+
+func (p *ClientProxy) setStatus(status ProxyStatus) {
+	p.lock.Lock()
+	changed := p.status != status
+	p.status = status
+	p.lock.Unlock()
+
+	if changed {
+		Logger.Info("Proxy status changed", "status", status.String())
+		if p.OnStatus != nil {
+			p.OnStatus(status)
+		}
+	}
+}
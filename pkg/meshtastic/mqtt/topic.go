@@ -0,0 +1,128 @@
+package mqtt
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TopicKind is the subtree of the Meshtastic MQTT topic hierarchy a message was published to.
+type TopicKind string
+
+const (
+	// TopicEncrypted carries protobuf ServiceEnvelope messages ("/2/e/").
+	TopicEncrypted TopicKind = "e"
+	// TopicLegacyEncrypted carries protobuf ServiceEnvelope messages from older firmware ("/2/c/").
+	TopicLegacyEncrypted TopicKind = "c"
+	// TopicJSON carries decoded packets serialized as JSON ("/2/json/").
+	TopicJSON TopicKind = "json"
+	// TopicMap carries ServiceEnvelope messages with map reports ("/2/map/").
+	TopicMap TopicKind = "map"
+	// TopicStatus carries gateway online/offline status messages ("/2/stat/").
+	TopicStatus TopicKind = "stat"
+)
+
+// Topic describes a parsed Meshtastic MQTT topic, "<root>[/<region>]/2/<kind>/<channel>/<gateway>".
+type Topic struct {
+	// Region is the part of the topic between the root and the protocol version, if any.
+	Region    string
+	Kind      TopicKind
+	ChannelID string
+	GatewayID string
+}
+
+// ParseTopic parses a topic relative to the root topic.
+func ParseTopic(root, topic string) (Topic, error) {
+	rest, ok := strings.CutPrefix(topic, root+"/")
+	if !ok {
+		return Topic{}, fmt.Errorf("topic %q is outside of root %q", topic, root)
+	}
+
+	parts := strings.Split(rest, "/")
+	version := slices.Index(parts, "2")
+	if version < 0 || version == len(parts)-1 {
+		return Topic{}, fmt.Errorf("topic %q has no protocol version", topic)
+	}
+	t := Topic{
+		Region: strings.Join(parts[:version], "/"),
+		Kind:   TopicKind(parts[version+1]),
+	}
+	parts = parts[version+1:]
+	switch t.Kind {
+	case TopicEncrypted, TopicLegacyEncrypted, TopicJSON:
+		if len(parts) > 1 {
+			t.ChannelID = parts[1]
+		}
+		if len(parts) > 2 {
+			t.GatewayID = parts[2]
+		}
+	case TopicStatus:
+		if len(parts) > 1 {
+			t.GatewayID = parts[1]
+		}
+	case TopicMap:
+	default:
+		return Topic{}, fmt.Errorf("unknown topic kind %q", t.Kind)
+	}
+	return t, nil
+}
+
+// String formats the topic relative to the root topic.
+func (t Topic) String(root string) string {
+	if t.Region != "" {
+		root += "/" + t.Region
+	}
+	switch t.Kind {
+	case TopicMap:
+		return root + "/2/map/"
+	case TopicStatus:
+		return fmt.Sprintf("%s/2/stat/%s", root, t.GatewayID)
+	default:
+		return fmt.Sprintf("%s/2/%s/%s/%s", root, t.Kind, t.ChannelID, t.GatewayID)
+	}
+}
+
+// Subscription selects a part of the topic hierarchy to receive. Empty fields match anything.
+type Subscription struct {
+	// Region is appended to the root topic, e.g. "EU_868" with root topic "msh". If empty,
+	// any single-level region matches when other fields are set. Topics without a region or
+	// with a multi-level region, such as "US/CA", need an explicit Region then.
+	Region string
+	// Kind is the topic subtree, e.g. TopicEncrypted or TopicJSON.
+	Kind TopicKind
+	// ChannelID is the channel name.
+	ChannelID string
+	// GatewayID is the ID of the uplinking gateway, e.g. "!a1b2c3d4".
+	GatewayID string
+	// QoS is the MQTT quality of service level for the subscription.
+	QoS byte
+}
+
+// Filter returns the MQTT topic filter of the subscription.
+func (s Subscription) Filter(root string) string {
+	if s.Kind == "" && s.ChannelID == "" && s.GatewayID == "" {
+		if s.Region != "" {
+			root += "/" + s.Region
+		}
+		return root + "/#"
+	}
+
+	root += "/" + wildcard(s.Region)
+
+	switch s.Kind {
+	case TopicMap:
+		return root + "/2/map/#"
+	case TopicStatus:
+		return fmt.Sprintf("%s/2/stat/%s", root, wildcard(s.GatewayID))
+	default:
+		return fmt.Sprintf("%s/2/%s/%s/%s", root, wildcard(string(s.Kind)), wildcard(s.ChannelID), wildcard(s.GatewayID))
+	}
+}
+
+// wildcard replaces an empty topic level with a single-level wildcard.
+func wildcard(level string) string {
+	if level == "" {
+		return "+"
+	}
+	return level
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
 	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
 	protobuf "google.golang.org/protobuf/proto"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,9 +29,51 @@ type Transport struct {
 	SendOpts SendPacketOptions
 	// BufferSize is the internal messages queue size.
 	BufferSize int
+	// Subscriptions selects the topics to receive. Defaults to everything under RootTopic.
+	Subscriptions []Subscription
+	// OverflowPolicy defines what happens to incoming messages when the queue is full.
+	OverflowPolicy OverflowPolicy
 
 	client     mqtt.Client
 	messagesCh chan mqtt.Message
+	stats      transportStats
+}
+
+// OverflowPolicy defines how the transport handles incoming messages when its queue is full.
+type OverflowPolicy int
+
+const (
+	// Block waits until there is room in the queue. It stalls the MQTT client until
+	// the consumer catches up. This is the default.
+	Block OverflowPolicy = iota
+	// DropOldest discards the oldest queued message to make room for the new one.
+	DropOldest
+	// DropNewest discards the incoming message.
+	DropNewest
+)
+
+// Stats holds counters of incoming messages.
+type Stats struct {
+	// Received is the number of messages received from the broker.
+	Received uint64
+	// Dropped is the number of messages discarded due to queue overflow.
+	Dropped uint64
+	// Queued is the number of messages currently waiting in the queue.
+	Queued int
+}
+
+type transportStats struct {
+	received atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+// Stats returns counters of incoming messages.
+func (mt *Transport) Stats() Stats {
+	return Stats{
+		Received: mt.stats.received.Load(),
+		Dropped:  mt.stats.dropped.Load(),
+		Queued:   len(mt.messagesCh),
+	}
 }
 
 // SendPacketOptions holds configuration options for sending a mesh packet.
@@ -45,13 +88,32 @@ func (mt *Transport) SendEnvelope(envelope *proto.ServiceEnvelope) error {
 		return fmt.Errorf("marshalling error: %w", err)
 	}
 
-	topic := fmt.Sprintf("%s/2/e/%s/%s", mt.RootTopic, envelope.GetChannelId(), envelope.GetGatewayId())
-	token := mt.client.Publish(topic, 0, false, msgData)
+	topic := Topic{Kind: TopicEncrypted, ChannelID: envelope.GetChannelId(), GatewayID: envelope.GetGatewayId()}
+	return mt.publish(topic.String(mt.RootTopic), msgData)
+}
+
+// SendMapEnvelope sends an envelope with a map report to the map topic.
+func (mt *Transport) SendMapEnvelope(envelope *proto.ServiceEnvelope) error {
+	msgData, err := protobuf.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("marshalling error: %w", err)
+	}
+	return mt.publish(Topic{Kind: TopicMap}.String(mt.RootTopic), msgData)
+}
+
+// publish sends a raw message to the topic and waits for completion.
+func (mt *Transport) publish(topic string, payload []byte) error {
+	if mt.client == nil {
+		return ErrNotConnected
+	}
+	token := mt.client.Publish(topic, 0, false, payload)
 	<-token.Done()
 	return token.Error()
 }
 
 // ReceiveEnvelope receives a envelope from MQTT.
+// Messages are parsed according to their topic: protobuf envelopes from the "e", "c" and "map"
+// subtrees are returned as is, and JSON messages are converted into envelopes with decoded packets.
 func (mt *Transport) ReceiveEnvelope(ctx context.Context) (*proto.ServiceEnvelope, error) {
 	if mt.client == nil || !mt.client.IsConnected() || mt.messagesCh == nil {
 		return nil, ErrNotConnected
@@ -65,12 +127,28 @@ func (mt *Transport) ReceiveEnvelope(ctx context.Context) (*proto.ServiceEnvelop
 			return nil, ErrNotConnected
 		}
 
+		return mt.parseMessage(msg)
+	}
+}
+
+// parseMessage converts an MQTT message into a service envelope based on its topic.
+func (mt *Transport) parseMessage(msg mqtt.Message) (*proto.ServiceEnvelope, error) {
+	topic, err := ParseTopic(mt.RootTopic, msg.Topic())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", meshtastic.ErrInvalidPacketFormat, err)
+	}
+
+	switch topic.Kind {
+	case TopicEncrypted, TopicLegacyEncrypted, TopicMap:
 		envelope := new(proto.ServiceEnvelope)
 		if err := protobuf.Unmarshal(msg.Payload(), envelope); err != nil {
 			return nil, meshtastic.ErrInvalidPacketFormat
 		}
-
 		return envelope, nil
+	case TopicJSON:
+		return decodeJSONEnvelope(topic, msg.Payload())
+	default:
+		return nil, fmt.Errorf("%w: no packets in %q topic", meshtastic.ErrInvalidPacketFormat, topic.Kind)
 	}
 }
 
@@ -85,7 +163,7 @@ func (mt *Transport) ReceiveFromMesh(ctx context.Context) (*proto.MeshPacket, er
 
 // Connect establishes an MQTT connection to the broker.
 // It generates a random client ID, connects to the broker, and subscribes
-// to all subtopics under the RootTopic.
+// to the configured Subscriptions, or to all subtopics under the RootTopic.
 func (mt *Transport) Connect() error {
 	if mt.client != nil && mt.client.IsConnected() {
 		return nil
@@ -133,15 +211,24 @@ func (mt *Transport) handleMessages() error {
 	if mt.client == nil || !mt.client.IsConnected() {
 		return errors.New("connection is not established")
 	}
-	Logger.Debug("Connection established. Creating subscription to root topic")
+	Logger.Debug("Connection established. Creating subscriptions")
+
+	subscriptions := mt.Subscriptions
+	if len(subscriptions) == 0 {
+		subscriptions = []Subscription{{}}
+	}
+	filters := make(map[string]byte, len(subscriptions))
+	for _, sub := range subscriptions {
+		filters[sub.Filter(mt.RootTopic)] = sub.QoS
+	}
 
-	token := mt.client.Subscribe(mt.RootTopic+"/#", 0, mt.handleMessage)
+	token := mt.client.SubscribeMultiple(filters, mt.handleMessage)
 	<-token.Done()
 	if err := token.Error(); err != nil {
 		return fmt.Errorf("failed to subscribe to topic: %w", err)
 	}
 
-	Logger.Debug("Subscribed to the root topic")
+	Logger.Debug("Subscribed to topics", "count", len(filters))
 	return nil
 }
 
@@ -161,5 +248,31 @@ func (mt *Transport) Disconnect() {
 
 func (mt *Transport) handleMessage(_ mqtt.Client, message mqtt.Message) {
 	Logger.Debug("A new message received")
-	mt.messagesCh <- message
+	mt.stats.received.Add(1)
+
+	switch mt.OverflowPolicy {
+	case DropNewest:
+		select {
+		case mt.messagesCh <- message:
+		default:
+			mt.stats.dropped.Add(1)
+			Logger.Warn("Messages queue is full. Incoming message dropped")
+		}
+	case DropOldest:
+		for {
+			select {
+			case mt.messagesCh <- message:
+				return
+			default:
+			}
+			select {
+			case <-mt.messagesCh:
+				mt.stats.dropped.Add(1)
+				Logger.Warn("Messages queue is full. Oldest message dropped")
+			default:
+			}
+		}
+	default:
+		mt.messagesCh <- message
+	}
 }
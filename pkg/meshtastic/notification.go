@@ -0,0 +1,237 @@
+package meshtastic
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// Levels of firmware log records and notifications which have no slog counterpart.
+const (
+	LevelTrace    = slog.LevelDebug - 4
+	LevelCritical = slog.LevelError + 4
+)
+
+// LogLevel converts a firmware log level into a slog level. Unset levels are treated as info.
+func LogLevel(level proto.LogRecord_Level) slog.Level {
+	switch level {
+	case proto.LogRecord_TRACE:
+		return LevelTrace
+	case proto.LogRecord_DEBUG:
+		return slog.LevelDebug
+	case proto.LogRecord_WARNING:
+		return slog.LevelWarn
+	case proto.LogRecord_ERROR:
+		return slog.LevelError
+	case proto.LogRecord_CRITICAL:
+		return LevelCritical
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// NotificationKind is the type of event a client notification reports.
+type NotificationKind int
+
+const (
+	// NotificationText is a free-text notification.
+	NotificationText NotificationKind = iota
+	// NotificationKeyVerificationNumberInform carries a security number to show to the operator.
+	NotificationKeyVerificationNumberInform
+	// NotificationKeyVerificationNumberRequest asks for the security number of the remote node.
+	NotificationKeyVerificationNumberRequest
+	// NotificationKeyVerificationFinal carries verification characters to compare.
+	NotificationKeyVerificationFinal
+	// NotificationDuplicatedPublicKey reports that another node uses the public key of the node.
+	NotificationDuplicatedPublicKey
+	// NotificationLowEntropyKey reports that the key of the node is known to be weak.
+	NotificationLowEntropyKey
+)
+
+var notificationKindNames = map[NotificationKind]string{
+	NotificationText:                         "text",
+	NotificationKeyVerificationNumberInform:  "key_verification_number_inform",
+	NotificationKeyVerificationNumberRequest: "key_verification_number_request",
+	NotificationKeyVerificationFinal:         "key_verification_final",
+	NotificationDuplicatedPublicKey:          "duplicated_public_key",
+	NotificationLowEntropyKey:                "low_entropy_key",
+}
+
+func (k NotificationKind) String() string {
+	return notificationKindNames[k]
+}
+
+// Security reports whether the notification kind is a warning about keys of the node.
+func (k NotificationKind) Security() bool {
+	return k == NotificationDuplicatedPublicKey || k == NotificationLowEntropyKey
+}
+
+// Notification is a client notification sent by the firmware.
+type Notification struct {
+	// Node is the number of the node which sent the notification.
+	Node uint32
+	Kind NotificationKind
+	// Level is the severity of the notification. Security warnings without a level
+	// are reported as warnings.
+	Level slog.Level
+	// ReplyID is the ID of the packet the notification refers to, zero if none.
+	ReplyID uint32
+	Time    time.Time
+	Message string
+	// Raw is the original notification, holding kind-specific fields.
+	Raw *proto.ClientNotification
+}
+
+// NewNotification converts a client notification received from the node.
+func NewNotification(node uint32, notification *proto.ClientNotification) Notification {
+	n := Notification{
+		Node:    node,
+		Level:   LogLevel(notification.GetLevel()),
+		ReplyID: notification.GetReplyId(),
+		Message: notification.GetMessage(),
+		Raw:     notification,
+	}
+	if t := notification.GetTime(); t != 0 {
+		n.Time = time.Unix(int64(t), 0)
+	} else {
+		n.Time = time.Now()
+	}
+
+	switch notification.GetPayloadVariant().(type) {
+	case *proto.ClientNotification_KeyVerificationNumberInform:
+		n.Kind = NotificationKeyVerificationNumberInform
+	case *proto.ClientNotification_KeyVerificationNumberRequest:
+		n.Kind = NotificationKeyVerificationNumberRequest
+	case *proto.ClientNotification_KeyVerificationFinal:
+		n.Kind = NotificationKeyVerificationFinal
+	case *proto.ClientNotification_DuplicatedPublicKey:
+		n.Kind = NotificationDuplicatedPublicKey
+	case *proto.ClientNotification_LowEntropyKey:
+		n.Kind = NotificationLowEntropyKey
+	}
+	if n.Kind.Security() && notification.GetLevel() == proto.LogRecord_UNSET {
+		n.Level = slog.LevelWarn
+	}
+	return n
+}
+
+// NotificationHandler handles client notifications.
+type NotificationHandler func(Notification)
+
+// LogSecurityNotifications returns a handler logging security warnings, such as duplicated
+// or weak keys, with the node they were reported by. Other notifications are ignored.
+func LogSecurityNotifications(logger *slog.Logger) NotificationHandler {
+	return func(n Notification) {
+		if !n.Kind.Security() {
+			return
+		}
+		logger.Log(context.Background(), n.Level, "Security notification",
+			"node", FormatNodeID(n.Node),
+			"kind", n.Kind.String(),
+			"message", n.Message,
+		)
+	}
+}
+
+// logSecurityNotification logs security warnings with Logger. It is used when no handler
+// is configured, so that warnings about keys of the node are not lost.
+func logSecurityNotification(n Notification) {
+	if !n.Kind.Security() {
+		return
+	}
+	args := []any{"kind", n.Kind.String(), "message", n.Message}
+	if n.Node != 0 {
+		args = append(args, "node", FormatNodeID(n.Node))
+	}
+	if n.Level >= slog.LevelError {
+		Logger.Error("Security notification", args...)
+	} else {
+		Logger.Warn("Security notification", args...)
+	}
+}
+
+// NotificationReceiver handles client notifications read from the radio. Device implements it.
+type NotificationReceiver interface {
+	HandleNotification(notification *proto.ClientNotification)
+}
+
+// HandleNotificationFrame passes the client notification carried by the frame to the receiver
+// and reports whether the frame carried one. If the receiver is nil, security warnings are
+// logged with Logger. Components reading frames from a transport use it to not drop
+// notifications along with frames they are not interested in.
+func HandleNotificationFrame(receiver NotificationReceiver, frame *proto.FromRadio) bool {
+	notification := frame.GetClientNotification()
+	if notification == nil {
+		return false
+	}
+	if receiver == nil {
+		logSecurityNotification(NewNotification(0, notification))
+	} else {
+		receiver.HandleNotification(notification)
+	}
+	return true
+}
+
+// Notifications dispatches client notifications to a handler and to waiters for
+// notifications about specific packets. It is safe for concurrent use.
+type Notifications struct {
+	// Handler receives every notification. If nil, security warnings are logged with Logger.
+	Handler NotificationHandler
+
+	lock    sync.Mutex
+	waiters map[uint32][]chan Notification
+}
+
+// Handle dispatches the notification.
+func (n *Notifications) Handle(notification Notification) {
+	if n.Handler != nil {
+		n.Handler(notification)
+	} else {
+		logSecurityNotification(notification)
+	}
+
+	if notification.ReplyID == 0 {
+		return
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, waiter := range n.waiters[notification.ReplyID] {
+		select {
+		case waiter <- notification:
+		default:
+		}
+	}
+}
+
+// Watch returns notifications referencing the packet ID. It should be called before
+// sending the packet. The returned function stops watching.
+func (n *Notifications) Watch(packetID uint32) (<-chan Notification, func()) {
+	waiter := make(chan Notification, 4)
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.waiters == nil {
+		n.waiters = make(map[uint32][]chan Notification)
+	}
+	n.waiters[packetID] = append(n.waiters[packetID], waiter)
+
+	return waiter, func() {
+		n.lock.Lock()
+		defer n.lock.Unlock()
+		waiters := n.waiters[packetID]
+		for i, w := range waiters {
+			if w == waiter {
+				waiters = append(waiters[:i], waiters[i+1:]...)
+				break
+			}
+		}
+		if len(waiters) == 0 {
+			delete(n.waiters, packetID)
+		} else {
+			n.waiters[packetID] = waiters
+		}
+	}
+}
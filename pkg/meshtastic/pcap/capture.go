@@ -0,0 +1,59 @@
+package pcap
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// envelopeReceiver is implemented by receivers which expose MQTT service envelopes,
+// such as mqtt.Transport. Envelopes carry the gateway ID which is lost in a bare MeshPacket.
+type envelopeReceiver interface {
+	ReceiveEnvelope(ctx context.Context) (*proto.ServiceEnvelope, error)
+}
+
+// Capture reads packets from the receiver and writes them to the writer until the context
+// is cancelled or the receiver returns an error.
+func Capture(ctx context.Context, receiver meshtastic.PacketReceiver, w *Writer) error {
+	for {
+		packet, meta, err := receive(ctx, receiver)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			Logger.Debug("Skipping malformed packet")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if packet == nil {
+			continue
+		}
+
+		if err = w.WritePacket(time.Now(), packet, meta); err != nil {
+			return err
+		}
+		Logger.Debug("Packet captured", "id", packet.GetId())
+	}
+}
+
+func receive(ctx context.Context, receiver meshtastic.PacketReceiver) (*proto.MeshPacket, Metadata, error) {
+	if er, ok := receiver.(envelopeReceiver); ok {
+		envelope, err := er.ReceiveEnvelope(ctx)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		packet := envelope.GetPacket()
+		meta := MetadataFromPacket(packet)
+		meta.ViaMQTT = true
+		meta.GatewayID = envelope.GetGatewayId()
+		return packet, meta, nil
+	}
+
+	packet, err := receiver.ReceiveFromMesh(ctx)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	return packet, MetadataFromPacket(packet), nil
+}
@@ -0,0 +1,152 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// LinkType is the pcapng link type used for Meshtastic captures (LINKTYPE_USER0).
+// Wireshark can be pointed at a dissector for it through the DLT_USER preferences table.
+const LinkType = 147
+
+// PseudoHeaderVersion is the version of the pseudo-header layout written by this package.
+const PseudoHeaderVersion = 1
+
+// pseudoHeaderFixedLen is the length of the fixed part of the pseudo-header.
+const pseudoHeaderFixedLen = 20
+
+// PayloadType describes how the bytes following the pseudo-header are encoded.
+type PayloadType uint8
+
+const (
+	// PayloadMeshPacket means the payload is a protobuf-encoded MeshPacket.
+	PayloadMeshPacket PayloadType = 1
+	// PayloadRadioFrame means the payload is the on-air LoRa frame: a 16-byte Meshtastic
+	// radio header followed by the encrypted data.
+	PayloadRadioFrame PayloadType = 2
+)
+
+// Pseudo-header flags.
+const (
+	FlagViaMQTT   uint8 = 1 << 0
+	FlagEncrypted uint8 = 1 << 1
+	FlagWantAck   uint8 = 1 << 2
+	FlagPKI       uint8 = 1 << 3
+)
+
+// Metadata holds reception details stored along with a packet in the pseudo-header.
+// RSSI, SNR and Channel left zero are taken from the packet itself.
+type Metadata struct {
+	// RSSI is the received signal strength, in dBm.
+	RSSI int32
+	// SNR is the received signal-to-noise ratio, in dB.
+	SNR float32
+	// Channel is the channel index (or channel hash for encrypted packets).
+	Channel uint32
+	// ViaMQTT is set when the packet was received from an MQTT broker.
+	ViaMQTT bool
+	// GatewayID is the ID of the node which uplinked the packet to MQTT, e.g. "!a1b2c3d4".
+	GatewayID string
+}
+
+// MetadataFromPacket fills metadata with reception details found in the packet.
+func MetadataFromPacket(packet *proto.MeshPacket) Metadata {
+	return Metadata{
+		RSSI:    packet.GetRxRssi(),
+		SNR:     packet.GetRxSnr(),
+		Channel: packet.GetChannel(),
+		ViaMQTT: packet.GetViaMqtt(),
+	}
+}
+
+// encodeRecord builds the record data: the pseudo-header followed by the packet payload.
+//
+// The pseudo-header layout (all integers are little-endian):
+//
+//	offset  size  field
+//	0       1     version (PseudoHeaderVersion)
+//	1       1     payload type (PayloadType)
+//	2       1     flags (Flag* constants)
+//	3       1     hop start
+//	4       2     total pseudo-header length, including the gateway ID
+//	6       2     reserved, zero
+//	8       4     RSSI, int32 dBm
+//	12      4     SNR, IEEE 754 float32 dB
+//	16      4     channel
+//	20      n     gateway ID, UTF-8, not terminated
+func encodeRecord(packet *proto.MeshPacket, meta Metadata) ([]byte, error) {
+	payloadType := PayloadMeshPacket
+	var payload []byte
+	if encrypted := packet.GetEncrypted(); encrypted != nil {
+		payloadType = PayloadRadioFrame
+		payload = radioFrame(packet, encrypted)
+	} else {
+		var err error
+		payload, err = protobuf.Marshal(packet)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if meta.RSSI == 0 {
+		meta.RSSI = packet.GetRxRssi()
+	}
+	if meta.SNR == 0 {
+		meta.SNR = packet.GetRxSnr()
+	}
+	if meta.Channel == 0 {
+		meta.Channel = packet.GetChannel()
+	}
+
+	var flags uint8
+	if meta.ViaMQTT || packet.GetViaMqtt() {
+		flags |= FlagViaMQTT
+	}
+	if payloadType == PayloadRadioFrame {
+		flags |= FlagEncrypted
+	}
+	if packet.GetWantAck() {
+		flags |= FlagWantAck
+	}
+	if packet.GetPkiEncrypted() {
+		flags |= FlagPKI
+	}
+
+	headerLen := pseudoHeaderFixedLen + len(meta.GatewayID)
+	buf := make([]byte, headerLen, headerLen+len(payload))
+	buf[0] = PseudoHeaderVersion
+	buf[1] = byte(payloadType)
+	buf[2] = flags
+	buf[3] = byte(packet.GetHopStart())
+	binary.LittleEndian.PutUint16(buf[4:], uint16(headerLen))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(meta.RSSI))
+	binary.LittleEndian.PutUint32(buf[12:], math.Float32bits(meta.SNR))
+	binary.LittleEndian.PutUint32(buf[16:], meta.Channel)
+	copy(buf[pseudoHeaderFixedLen:], meta.GatewayID)
+	return append(buf, payload...), nil
+}
+
+// radioFrame reconstructs the on-air frame of an encrypted packet.
+func radioFrame(packet *proto.MeshPacket, encrypted []byte) []byte {
+	frame := make([]byte, 16, 16+len(encrypted))
+	binary.LittleEndian.PutUint32(frame[0:], packet.GetTo())
+	binary.LittleEndian.PutUint32(frame[4:], packet.GetFrom())
+	binary.LittleEndian.PutUint32(frame[8:], packet.GetId())
+
+	flags := byte(packet.GetHopLimit() & 0x07)
+	if packet.GetWantAck() {
+		flags |= 0x08
+	}
+	if packet.GetViaMqtt() {
+		flags |= 0x10
+	}
+	flags |= byte(packet.GetHopStart()&0x07) << 5
+	frame[12] = flags
+	frame[13] = byte(packet.GetChannel())
+	frame[14] = byte(packet.GetNextHop())
+	frame[15] = byte(packet.GetRelayNode())
+	return append(frame, encrypted...)
+}
@@ -0,0 +1,134 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// pcapng block types and option codes.
+const (
+	blockSectionHeader      = 0x0A0D0D0A
+	blockInterfaceDesc      = 0x00000001
+	blockEnhancedPacket     = 0x00000006
+	byteOrderMagic          = 0x1A2B3C4D
+	optEndOfOpt             = 0
+	optComment              = 1
+	optShbUserAppl          = 4
+	optIfName               = 2
+	optIfTsResol            = 9
+	defaultSnapLen          = 0
+	timestampResolutionUsec = 6
+)
+
+// NewWriter creates a pcapng writer and writes the section header and interface description
+// blocks to w.
+func NewWriter(w io.Writer, interfaceName string) (*Writer, error) {
+	pw := &Writer{w: w}
+
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:], byteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:], 1) // major version
+	binary.LittleEndian.PutUint16(shb[6:], 0) // minor version
+	binary.LittleEndian.PutUint64(shb[8:], 0xFFFFFFFFFFFFFFFF)
+	shb = appendOptions(shb, option{optShbUserAppl, []byte("meshtastic-go")})
+	if err := pw.writeBlock(blockSectionHeader, shb); err != nil {
+		return nil, fmt.Errorf("failed to write section header: %w", err)
+	}
+
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:], LinkType)
+	binary.LittleEndian.PutUint32(idb[4:], defaultSnapLen)
+	options := []option{{optIfTsResol, []byte{timestampResolutionUsec}}}
+	if interfaceName != "" {
+		options = append(options, option{optIfName, []byte(interfaceName)})
+	}
+	idb = appendOptions(idb, options...)
+	if err := pw.writeBlock(blockInterfaceDesc, idb); err != nil {
+		return nil, fmt.Errorf("failed to write interface description: %w", err)
+	}
+
+	return pw, nil
+}
+
+// Writer writes Meshtastic packets into a pcapng stream.
+// It is safe for concurrent use.
+type Writer struct {
+	w    io.Writer
+	lock sync.Mutex
+}
+
+// WritePacket writes a single packet record, captured at the given time.
+func (pw *Writer) WritePacket(timestamp time.Time, packet *proto.MeshPacket, meta Metadata) error {
+	data, err := encodeRecord(packet, meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode packet: %w", err)
+	}
+
+	usec := uint64(timestamp.UnixMicro())
+	epb := make([]byte, 20, 20+len(data)+3)
+	binary.LittleEndian.PutUint32(epb[0:], 0) // interface ID
+	binary.LittleEndian.PutUint32(epb[4:], uint32(usec>>32))
+	binary.LittleEndian.PutUint32(epb[8:], uint32(usec))
+	binary.LittleEndian.PutUint32(epb[12:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(epb[16:], uint32(len(data)))
+	epb = append(epb, data...)
+	epb = pad(epb)
+	epb = appendOptions(epb, option{optComment, []byte(describe(packet))})
+
+	return pw.writeBlock(blockEnhancedPacket, epb)
+}
+
+// writeBlock writes a generic pcapng block with the given body.
+func (pw *Writer) writeBlock(blockType uint32, body []byte) error {
+	totalLen := uint32(12 + len(body))
+	buf := make([]byte, 0, totalLen)
+	buf = binary.LittleEndian.AppendUint32(buf, blockType)
+	buf = binary.LittleEndian.AppendUint32(buf, totalLen)
+	buf = append(buf, body...)
+	buf = binary.LittleEndian.AppendUint32(buf, totalLen)
+
+	pw.lock.Lock()
+	defer pw.lock.Unlock()
+	_, err := pw.w.Write(buf)
+	return err
+}
+
+type option struct {
+	code  uint16
+	value []byte
+}
+
+// appendOptions encodes options followed by the end-of-options marker.
+func appendOptions(buf []byte, options ...option) []byte {
+	for _, opt := range options {
+		buf = binary.LittleEndian.AppendUint16(buf, opt.code)
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(len(opt.value)))
+		buf = append(buf, opt.value...)
+		buf = pad(buf)
+	}
+	buf = binary.LittleEndian.AppendUint16(buf, optEndOfOpt)
+	return binary.LittleEndian.AppendUint16(buf, 0)
+}
+
+// pad aligns the buffer length to 32 bits.
+func pad(buf []byte) []byte {
+	for len(buf)%4 != 0 {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+// describe returns a short human-readable summary of the packet, shown as a record comment.
+func describe(packet *proto.MeshPacket) string {
+	summary := fmt.Sprintf("id=%08x from=!%08x to=!%08x hops=%d/%d",
+		packet.GetId(), packet.GetFrom(), packet.GetTo(), packet.GetHopLimit(), packet.GetHopStart())
+	if decoded := packet.GetDecoded(); decoded != nil {
+		summary += " port=" + decoded.GetPortnum().String()
+	}
+	return summary
+}
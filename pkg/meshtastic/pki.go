@@ -0,0 +1,200 @@
+package meshtastic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	// PKIOverhead is the number of bytes PKI encryption adds to a payload: the authentication
+	// tag and the extra nonce.
+	PKIOverhead = pkiTagSize + 4
+
+	pkiTagSize   = 8
+	pkiNonceSize = 13
+)
+
+// ErrPKIDecryption is returned when a PKI encrypted payload fails authentication.
+var ErrPKIDecryption = errors.New("PKI decryption failed")
+
+// GenerateKeyPair generates a Curve25519 key pair for direct messages and remote admin,
+// in the format of Config_SecurityConfig.
+func GenerateKeyPair() (privateKey, publicKey []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key.Bytes(), key.PublicKey().Bytes(), nil
+}
+
+// PublicKey returns the Curve25519 public key of the private key.
+func PublicKey(privateKey []byte) ([]byte, error) {
+	key, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return key.PublicKey().Bytes(), nil
+}
+
+// EncryptPKI encrypts the decoded payload of a MeshPacket for the node owning remotePublicKey.
+// The packet must have its sender and ID set. It returns the encrypted bytes suitable for
+// the MeshPacket_Encrypted payload variant of a packet with PkiEncrypted set.
+func EncryptPKI(packet *proto.MeshPacket, privateKey, remotePublicKey []byte) ([]byte, error) {
+	plain, err := protobuf.Marshal(packet.GetDecoded())
+	if err != nil {
+		return nil, fmt.Errorf("marshalling error: %w", err)
+	}
+
+	block, err := pkiCipher(privateKey, remotePublicKey)
+	if err != nil {
+		return nil, err
+	}
+	var extraNonce [4]byte
+	if _, err = rand.Read(extraNonce[:]); err != nil {
+		return nil, err
+	}
+
+	nonce := pkiNonce(packet, extraNonce[:])
+	encrypted := ccmSeal(block, nonce, nil, plain, pkiTagSize)
+	return append(encrypted, extraNonce[:]...), nil
+}
+
+// DecryptPKI decrypts the PKI encrypted payload of a MeshPacket sent by the node owning
+// remotePublicKey.
+func DecryptPKI(packet *proto.MeshPacket, privateKey, remotePublicKey []byte) (*proto.Data, error) {
+	encrypted := packet.GetEncrypted()
+	if len(encrypted) < PKIOverhead {
+		return nil, ErrInvalidPacketFormat
+	}
+
+	block, err := pkiCipher(privateKey, remotePublicKey)
+	if err != nil {
+		return nil, err
+	}
+	extraNonce := encrypted[len(encrypted)-4:]
+	plain, ok := ccmOpen(block, pkiNonce(packet, extraNonce), nil, encrypted[:len(encrypted)-4], pkiTagSize)
+	if !ok {
+		return nil, ErrPKIDecryption
+	}
+
+	decryptedData := new(proto.Data)
+	if err = protobuf.Unmarshal(plain, decryptedData); err != nil {
+		return nil, ErrInvalidPacketFormat
+	}
+	return decryptedData, nil
+}
+
+// pkiCipher creates the AES-256 cipher keyed with the hashed X25519 shared secret.
+func pkiCipher(privateKey, remotePublicKey []byte) (cipher.Block, error) {
+	private, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	public, err := ecdh.X25519().NewPublicKey(remotePublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	secret, err := private.ECDH(public)
+	if err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256(secret)
+	return aes.NewCipher(key[:])
+}
+
+// pkiNonce builds the AES-CCM nonce of the packet. It is laid out like the AES-CTR nonce,
+// with the extra nonce in place of the high bits of the packet ID.
+func pkiNonce(packet *proto.MeshPacket, extraNonce []byte) []byte {
+	nonce := make([]byte, pkiNonceSize)
+	binary.LittleEndian.PutUint32(nonce[0:], packet.GetId())
+	copy(nonce[4:8], extraNonce)
+	binary.LittleEndian.PutUint32(nonce[8:], packet.GetFrom())
+	return nonce
+}
+
+// ccmSeal encrypts and authenticates plain with AES-CCM (RFC 3610). Firmware uses no
+// associated data. The nonce must be 13 bytes long. It returns the ciphertext followed
+// by the tag.
+func ccmSeal(block cipher.Block, nonce, aad, plain []byte, tagSize int) []byte {
+	tag := ccmMAC(block, nonce, aad, plain, tagSize)
+	out := make([]byte, len(plain), len(plain)+tagSize)
+	ccmCTR(block, nonce, out, plain, tag)
+	return append(out, tag...)
+}
+
+// ccmOpen authenticates and decrypts the output of ccmSeal.
+func ccmOpen(block cipher.Block, nonce, aad, sealed []byte, tagSize int) ([]byte, bool) {
+	if len(sealed) < tagSize {
+		return nil, false
+	}
+	tag := make([]byte, tagSize)
+	copy(tag, sealed[len(sealed)-tagSize:])
+	plain := make([]byte, len(sealed)-tagSize)
+	ccmCTR(block, nonce, plain, sealed[:len(plain)], tag)
+
+	if subtle.ConstantTimeCompare(tag, ccmMAC(block, nonce, aad, plain, tagSize)) != 1 {
+		return nil, false
+	}
+	return plain, true
+}
+
+// ccmMAC computes the CBC-MAC of the associated data and the message. Associated data
+// must be shorter than 0xff00 bytes.
+func ccmMAC(block cipher.Block, nonce, aad, msg []byte, tagSize int) []byte {
+	lengthSize := aes.BlockSize - 1 - len(nonce)
+	x := make([]byte, aes.BlockSize)
+	x[0] = byte((tagSize-2)/2)<<3 | byte(lengthSize-1)
+	if len(aad) > 0 {
+		x[0] |= 0x40
+	}
+	copy(x[1:], nonce)
+	for i, n := aes.BlockSize-1, len(msg); i > len(nonce); i, n = i-1, n>>8 {
+		x[i] = byte(n)
+	}
+	block.Encrypt(x, x)
+
+	if len(aad) > 0 {
+		// the associated data is prefixed with its length, both padded to whole blocks
+		ccmMACBlocks(block, x, binary.BigEndian.AppendUint16(nil, uint16(len(aad))), aad)
+	}
+	ccmMACBlocks(block, x, msg)
+	return x[:tagSize]
+}
+
+// ccmMACBlocks chains the concatenated data into the CBC-MAC state x, zero padding the
+// last block.
+func ccmMACBlocks(block cipher.Block, x []byte, data ...[]byte) {
+	var buf []byte
+	for _, d := range data {
+		buf = append(buf, d...)
+	}
+	for len(buf) > 0 {
+		n := subtle.XORBytes(x, x, buf)
+		buf = buf[n:]
+		block.Encrypt(x, x)
+	}
+}
+
+// ccmCTR encrypts src into dst with the CCM counter blocks and encrypts the tag in place.
+func ccmCTR(block cipher.Block, nonce, dst, src, tag []byte) {
+	counter := make([]byte, aes.BlockSize)
+	counter[0] = byte(aes.BlockSize - 2 - len(nonce))
+	copy(counter[1:], nonce)
+
+	s0 := make([]byte, aes.BlockSize)
+	block.Encrypt(s0, counter)
+	subtle.XORBytes(tag, tag, s0)
+
+	counter[aes.BlockSize-1] = 1
+	cipher.NewCTR(block, counter).XORKeyStream(dst, src)
+}
@@ -0,0 +1,175 @@
+package meshtastic
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid hex %q: %v", s, err)
+	}
+	return b
+}
+
+// TestCCMVectors checks the CCM implementation against the RFC 3610 packet vectors with
+// a 13 byte nonce and an 8 byte tag, as used by the firmware.
+func TestCCMVectors(t *testing.T) {
+	const key = "c0c1c2c3c4c5c6c7c8c9cacbcccdcecf"
+	tests := []struct {
+		name   string
+		nonce  string
+		input  string
+		aadLen int
+		output string
+	}{
+		{
+			name:   "packet 1",
+			nonce:  "00000003020100a0a1a2a3a4a5",
+			input:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e",
+			aadLen: 8,
+			output: "0001020304050607588c979a61c663d2f066d0c2c0f989806d5f6b61dac38417e8d12cfdf926e0",
+		},
+		{
+			name:   "packet 2",
+			nonce:  "00000004030201a0a1a2a3a4a5",
+			input:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			aadLen: 8,
+			output: "000102030405060772c91a36e135f8cf291ca894085c87e3cc15c439c9e43a3ba091d56e10400916",
+		},
+		{
+			name:   "packet 3",
+			nonce:  "00000005040302a0a1a2a3a4a5",
+			input:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+			aadLen: 8,
+			output: "000102030405060751b1e5f44a197d1da46b0f8e2d282ae871e838bb64da8596574adaa76fbd9fb0c5",
+		},
+		{
+			name:   "packet 4",
+			nonce:  "00000006050403a0a1a2a3a4a5",
+			input:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e",
+			aadLen: 12,
+			output: "000102030405060708090a0ba28c6865939a9a79faaa5c4c2a9d4a91cdac8c96c861b9c9e61ef1",
+		},
+		{
+			name:   "packet 5",
+			nonce:  "00000007060504a0a1a2a3a4a5",
+			input:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			aadLen: 12,
+			output: "000102030405060708090a0bdcf1fb7b5d9e23fb9d4e131253658ad86ebdca3e51e83f077d9c2d93",
+		},
+		{
+			name:   "packet 6",
+			nonce:  "00000008070605a0a1a2a3a4a5",
+			input:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+			aadLen: 12,
+			output: "000102030405060708090a0b6fc1b011f006568b5171a42d953d469b2570a4bd87405a0443ac91cb94",
+		},
+	}
+	block, err := aes.NewCipher(mustHex(t, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce := mustHex(t, tt.nonce)
+			input := mustHex(t, tt.input)
+			aad, plain := input[:tt.aadLen], input[tt.aadLen:]
+			want := mustHex(t, tt.output)[tt.aadLen:]
+
+			sealed := ccmSeal(block, nonce, aad, plain, pkiTagSize)
+			if !bytes.Equal(sealed, want) {
+				t.Fatalf("sealed %x, want %x", sealed, want)
+			}
+			opened, ok := ccmOpen(block, nonce, aad, sealed, pkiTagSize)
+			if !ok || !bytes.Equal(opened, plain) {
+				t.Fatalf("opened %x (ok %v), want %x", opened, ok, plain)
+			}
+
+			sealed[0] ^= 1
+			if _, ok = ccmOpen(block, nonce, aad, sealed, pkiTagSize); ok {
+				t.Error("tampered ciphertext authenticated")
+			}
+		})
+	}
+}
+
+func TestPKIRoundTrip(t *testing.T) {
+	alicePrivate, alicePublic, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobPrivate, bobPublic, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := &proto.Data{Portnum: proto.PortNum_TEXT_MESSAGE_APP, Payload: []byte("hello")}
+	packet := &proto.MeshPacket{
+		From:           0x11223344,
+		To:             0x55667788,
+		Id:             0x0badcafe,
+		PayloadVariant: &proto.MeshPacket_Decoded{Decoded: data},
+	}
+	encrypted, err := EncryptPKI(packet, alicePrivate, bobPublic)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if want := protobuf.Size(data) + PKIOverhead; len(encrypted) != want {
+		t.Errorf("encrypted %d bytes, want %d", len(encrypted), want)
+	}
+
+	received := &proto.MeshPacket{
+		From:           packet.From,
+		To:             packet.To,
+		Id:             packet.Id,
+		PkiEncrypted:   true,
+		PayloadVariant: &proto.MeshPacket_Encrypted{Encrypted: encrypted},
+	}
+	decrypted, err := DecryptPKI(received, bobPrivate, alicePublic)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if !protobuf.Equal(decrypted, data) {
+		t.Errorf("decrypted %v, want %v", decrypted, data)
+	}
+
+	// the nonce binds the payload to the sender and the packet ID
+	received.Id++
+	if _, err = DecryptPKI(received, bobPrivate, alicePublic); !errors.Is(err, ErrPKIDecryption) {
+		t.Errorf("got error %v for a changed packet ID, want ErrPKIDecryption", err)
+	}
+}
+
+// TestDecryptPKIFirmwarePacket decrypts a packet encrypted by the firmware, taken from
+// its crypto unit tests.
+func TestDecryptPKIFirmwarePacket(t *testing.T) {
+	privateKey := mustHex(t, "a00330633e63522f8a4d81ec6d9d1e6617f6c8ffd3a4c698229537d44e522277")
+	remotePublicKey := mustHex(t, "db18fc50eea47f00251cb784819a3cf5fc361882597f589f0d7ff820e8064457")
+	// the radio frame: to, from, ID, flags and the encrypted payload
+	frame := mustHex(t, "8c646d7a2909000062d6b2136b00000040df24abfcc30a17a3d9046726099e796a1c036a792b")
+
+	packet := &proto.MeshPacket{
+		To:             0x7a6d648c,
+		From:           0x0929,
+		Id:             0x13b2d662,
+		PkiEncrypted:   true,
+		PayloadVariant: &proto.MeshPacket_Encrypted{Encrypted: frame[PacketHeaderSize:]},
+	}
+	decrypted, err := DecryptPKI(packet, privateKey, remotePublicKey)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	want := &proto.Data{Portnum: proto.PortNum_TEXT_MESSAGE_APP, Payload: []byte("test"), Bitfield: protobuf.Uint32(0)}
+	if !protobuf.Equal(decrypted, want) {
+		t.Errorf("decrypted %v, want %v", decrypted, want)
+	}
+}
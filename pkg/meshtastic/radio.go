@@ -1,17 +1,119 @@
 package meshtastic
 
-// RadioPreset describes basic information about LoRa radio preset.
+import (
+	"errors"
+	"fmt"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// ErrInvalidPreset is returned for modem settings airtime can not be calculated for.
+var ErrInvalidPreset = errors.New("invalid radio preset")
+
+// RadioPreset describes a LoRa radio preset and its modem settings.
+//
+// Bandwidths are the ones used in sub-GHz regions. Firmware widens them in the LORA_24 region.
 type RadioPreset struct {
 	Name string
+	// Modem is the preset value in the LoRa config.
+	Modem proto.Config_LoRaConfig_ModemPreset
+	// SpreadingFactor is the LoRa spreading factor, from 7 to 12.
+	SpreadingFactor int
+	// Bandwidth is the channel bandwidth in Hz.
+	Bandwidth int
+	// CodingRate is the denominator of the 4/x coding rate, from 5 to 8.
+	CodingRate int
 }
 
 var (
-	PresetShortTurbo   = RadioPreset{Name: "ShortTurbo"}
-	PresetShortFast    = RadioPreset{Name: "ShortFast"}
-	PresetShortSlow    = RadioPreset{Name: "ShortSlow"}
-	PresetMediumFast   = RadioPreset{Name: "MediumFast"}
-	PresetMediumSlow   = RadioPreset{Name: "MediumSlow"}
-	PresetLongFast     = RadioPreset{Name: "LongFast"}
-	PresetLongModerate = RadioPreset{Name: "LongModerate"}
-	PresetLongSlow     = RadioPreset{Name: "LongSlow"}
+	PresetShortTurbo   = RadioPreset{Name: "ShortTurbo", Modem: proto.Config_LoRaConfig_SHORT_TURBO, SpreadingFactor: 7, Bandwidth: 500000, CodingRate: 5}
+	PresetShortFast    = RadioPreset{Name: "ShortFast", Modem: proto.Config_LoRaConfig_SHORT_FAST, SpreadingFactor: 7, Bandwidth: 250000, CodingRate: 5}
+	PresetShortSlow    = RadioPreset{Name: "ShortSlow", Modem: proto.Config_LoRaConfig_SHORT_SLOW, SpreadingFactor: 8, Bandwidth: 250000, CodingRate: 5}
+	PresetMediumFast   = RadioPreset{Name: "MediumFast", Modem: proto.Config_LoRaConfig_MEDIUM_FAST, SpreadingFactor: 9, Bandwidth: 250000, CodingRate: 5}
+	PresetMediumSlow   = RadioPreset{Name: "MediumSlow", Modem: proto.Config_LoRaConfig_MEDIUM_SLOW, SpreadingFactor: 10, Bandwidth: 250000, CodingRate: 5}
+	PresetLongFast     = RadioPreset{Name: "LongFast", Modem: proto.Config_LoRaConfig_LONG_FAST, SpreadingFactor: 11, Bandwidth: 250000, CodingRate: 5}
+	PresetLongModerate = RadioPreset{Name: "LongModerate", Modem: proto.Config_LoRaConfig_LONG_MODERATE, SpreadingFactor: 11, Bandwidth: 125000, CodingRate: 8}
+	PresetLongSlow     = RadioPreset{Name: "LongSlow", Modem: proto.Config_LoRaConfig_LONG_SLOW, SpreadingFactor: 12, Bandwidth: 125000, CodingRate: 8}
+	// PresetVeryLongSlow is deprecated in firmware, but still found in old configs.
+	PresetVeryLongSlow = RadioPreset{Name: "VeryLongSlow", Modem: proto.Config_LoRaConfig_VERY_LONG_SLOW, SpreadingFactor: 12, Bandwidth: 62500, CodingRate: 8}
 )
+
+// Presets lists the known radio presets, from the fastest to the slowest.
+var Presets = []RadioPreset{
+	PresetShortTurbo,
+	PresetShortFast,
+	PresetShortSlow,
+	PresetMediumFast,
+	PresetMediumSlow,
+	PresetLongFast,
+	PresetLongModerate,
+	PresetLongSlow,
+	PresetVeryLongSlow,
+}
+
+// PresetByModem returns the preset of the modem preset value.
+func PresetByModem(modem proto.Config_LoRaConfig_ModemPreset) (RadioPreset, bool) {
+	for _, preset := range Presets {
+		if preset.Modem == modem {
+			return preset, true
+		}
+	}
+	return RadioPreset{}, false
+}
+
+// LoRaPreset returns the modem settings of a LoRa config. Configs not using a preset
+// result in a preset named "Custom". It returns ErrInvalidPreset for unknown presets
+// and incomplete custom settings.
+func LoRaPreset(config *proto.Config_LoRaConfig) (RadioPreset, error) {
+	if config.GetUsePreset() {
+		preset, ok := PresetByModem(config.GetModemPreset())
+		if !ok {
+			return RadioPreset{}, fmt.Errorf("%w: unknown modem preset %v", ErrInvalidPreset, config.GetModemPreset())
+		}
+		return preset, nil
+	}
+	preset := RadioPreset{
+		Name:            "Custom",
+		SpreadingFactor: int(config.GetSpreadFactor()),
+		Bandwidth:       customBandwidth(config.GetBandwidth()),
+		CodingRate:      int(config.GetCodingRate()),
+	}
+	if err := preset.Validate(); err != nil {
+		return RadioPreset{}, err
+	}
+	return preset, nil
+}
+
+// Validate checks that the modem settings are complete and within LoRa limits.
+func (p RadioPreset) Validate() error {
+	switch {
+	case p.SpreadingFactor < 7 || p.SpreadingFactor > 12:
+		return fmt.Errorf("%w: spreading factor %d", ErrInvalidPreset, p.SpreadingFactor)
+	case p.Bandwidth <= 0:
+		return fmt.Errorf("%w: bandwidth %d Hz", ErrInvalidPreset, p.Bandwidth)
+	case p.CodingRate < 5 || p.CodingRate > 8:
+		return fmt.Errorf("%w: coding rate 4/%d", ErrInvalidPreset, p.CodingRate)
+	}
+	return nil
+}
+
+// customBandwidth converts the bandwidth of a LoRa config in kHz into Hz. Firmware
+// stores fractional bandwidths rounded down.
+func customBandwidth(khz uint32) int {
+	switch khz {
+	case 31:
+		return 31250
+	case 62:
+		return 62500
+	case 200:
+		return 203125
+	case 400:
+		return 406250
+	case 800:
+		return 812500
+	case 1600:
+		return 1625000
+	default:
+		return int(khz) * 1000
+	}
+}
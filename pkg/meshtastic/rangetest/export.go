@@ -0,0 +1,74 @@
+package rangetest
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/geojson"
+)
+
+var csvHeader = []string{
+	"time", "from", "seq", "rssi", "snr", "hops_away", "latitude", "longitude", "altitude",
+}
+
+// WriteCSV writes the records as CSV with a header row. Position columns are empty
+// for records without a receiver position.
+func WriteCSV(w io.Writer, records []Record) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := []string{
+			record.Received.UTC().Format(time.RFC3339),
+			meshtastic.FormatNodeID(record.From),
+			strconv.FormatUint(uint64(record.Seq), 10),
+			strconv.FormatInt(int64(record.RSSI), 10),
+			strconv.FormatFloat(float64(record.SNR), 'f', 2, 32),
+			"", "", "", "",
+		}
+		if record.HopsAway >= 0 {
+			row[5] = strconv.Itoa(record.HopsAway)
+		}
+		if pos := record.Position; pos != nil && pos.LatitudeI != nil && pos.LongitudeI != nil {
+			row[6] = strconv.FormatFloat(float64(pos.GetLatitudeI())*1e-7, 'f', 7, 64)
+			row[7] = strconv.FormatFloat(float64(pos.GetLongitudeI())*1e-7, 'f', 7, 64)
+			row[8] = strconv.FormatInt(int64(pos.GetAltitude()), 10)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// GeoJSON returns the records with a receiver position as point features, so the reception
+// quality can be drawn on a map.
+func GeoJSON(records []Record) geojson.FeatureCollection {
+	var features []geojson.Feature
+	for _, record := range records {
+		pos := record.Position
+		if pos == nil || pos.LatitudeI == nil || pos.LongitudeI == nil {
+			continue
+		}
+		properties := map[string]any{
+			"from": meshtastic.FormatNodeID(record.From),
+			"seq":  record.Seq,
+			"time": record.Received.UTC().Format(time.RFC3339),
+			"rssi": record.RSSI,
+			"snr":  record.SNR,
+		}
+		if record.HopsAway >= 0 {
+			properties["hops_away"] = record.HopsAway
+		}
+		point := geojson.Point(geojson.PositionI(pos.GetLatitudeI(), pos.GetLongitudeI(), pos.GetAltitude()))
+		features = append(features, geojson.NewFeature(point, properties))
+	}
+	return geojson.NewFeatureCollection(features...)
+}
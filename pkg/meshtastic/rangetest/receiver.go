@@ -0,0 +1,107 @@
+package rangetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// Record is a single received range test packet.
+type Record struct {
+	// From is the node number of the sender.
+	From uint32
+	// Seq is the sequence number of the packet.
+	Seq uint32
+	// Received is the reception time.
+	Received time.Time
+	// RSSI is the received signal strength in dBm.
+	RSSI int32
+	// SNR is the signal-to-noise ratio in dB.
+	SNR float32
+	// HopsAway is the number of relays the packet passed, or -1 if the sender's firmware
+	// does not report it.
+	HopsAway int
+	// Position is the receiver's position at reception time, if known.
+	Position *proto.Position
+}
+
+// Receiver records range test packets heard by the local node.
+// It is safe for concurrent use.
+type Receiver struct {
+	// Position returns the current position of the receiver. May be nil.
+	Position func() *proto.Position
+
+	lock    sync.RWMutex
+	records []Record
+}
+
+// Run records range test packets from the receiver until the context is cancelled
+// or the receiver fails.
+func (r *Receiver) Run(ctx context.Context, receiver meshtastic.PacketReceiver) error {
+	for {
+		packet, err := receiver.ReceiveFromMesh(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		r.Handle(packet)
+	}
+}
+
+// Handle records the packet if it is a range test packet. It returns false otherwise.
+func (r *Receiver) Handle(packet *proto.MeshPacket) (Record, bool) {
+	decoded := packet.GetDecoded()
+	if decoded.GetPortnum() != proto.PortNum_RANGE_TEST_APP {
+		return Record{}, false
+	}
+	seq, err := ParsePayload(decoded.GetPayload())
+	if err != nil {
+		Logger.Debug("Invalid range test packet", "from", meshtastic.FormatNodeID(packet.GetFrom()))
+		return Record{}, false
+	}
+
+	record := Record{
+		From:     packet.GetFrom(),
+		Seq:      seq,
+		Received: time.Now(),
+		RSSI:     packet.GetRxRssi(),
+		SNR:      packet.GetRxSnr(),
+		HopsAway: -1,
+	}
+	if packet.GetRxTime() != 0 {
+		record.Received = time.Unix(int64(packet.GetRxTime()), 0)
+	}
+	if packet.GetHopStart() != 0 {
+		record.HopsAway = int(packet.GetHopStart()) - int(packet.GetHopLimit())
+	}
+	if r.Position != nil {
+		record.Position = r.Position()
+	}
+
+	r.lock.Lock()
+	r.records = append(r.records, record)
+	r.lock.Unlock()
+	Logger.Debug("Range test packet received",
+		"from", meshtastic.FormatNodeID(record.From), "seq", seq, "rssi", record.RSSI, "snr", record.SNR)
+	return record, true
+}
+
+// Records returns all recorded packets in reception order.
+func (r *Receiver) Records() []Record {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]Record(nil), r.records...)
+}
+
+// Reset removes all recorded packets.
+func (r *Receiver) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.records = nil
+}
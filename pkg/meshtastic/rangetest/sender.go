@@ -0,0 +1,112 @@
+// Package rangetest implements the range test module on RANGE_TEST_APP: a sender of sequenced
+// packets and a receiver which records reception quality and computes link statistics.
+package rangetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// payloadPrefix starts the text of range test packets, followed by the sequence number.
+const payloadPrefix = "seq "
+
+// ErrInvalidPayload is returned when a range test packet does not carry a sequence number.
+var ErrInvalidPayload = errors.New("invalid range test payload")
+
+// Sender broadcasts range test packets with increasing sequence numbers.
+type Sender struct {
+	// Transport is used to send packets to the mesh.
+	Transport meshtastic.PacketSender
+	// Interval is the time between two packets.
+	Interval time.Duration
+	// Dest is the destination node number. Zero means broadcast.
+	Dest uint32
+	// ChannelIndex is the channel packets are sent on.
+	ChannelIndex uint32
+	// Count limits the number of sent packets. Zero means no limit.
+	Count uint32
+	// OnSent is called after each sent packet, if not nil.
+	OnSent func(seq uint32)
+}
+
+// NewSender creates a sender with the interval of the range test module configuration.
+func NewSender(transport meshtastic.PacketSender, config *proto.ModuleConfig_RangeTestConfig) *Sender {
+	return &Sender{
+		Transport: transport,
+		Interval:  time.Duration(config.GetSender()) * time.Second,
+	}
+}
+
+// Run sends packets starting with sequence number 1 until the context is cancelled,
+// the packet count is reached or sending fails.
+func (s *Sender) Run(ctx context.Context) error {
+	if s.Interval <= 0 {
+		return errors.New("range test interval is not set")
+	}
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for seq := uint32(1); s.Count == 0 || seq <= s.Count; seq++ {
+		if err := s.Send(ctx, seq); err != nil {
+			return err
+		}
+		if s.OnSent != nil {
+			s.OnSent(seq)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Send sends a single packet with the given sequence number.
+func (s *Sender) Send(ctx context.Context, seq uint32) error {
+	dest := s.Dest
+	if dest == 0 {
+		dest = meshtastic.BroadcastNodenum
+	}
+	err := s.Transport.SendToMesh(ctx, &proto.MeshPacket{
+		To:      dest,
+		Channel: s.ChannelIndex,
+		PayloadVariant: &proto.MeshPacket_Decoded{
+			Decoded: &proto.Data{
+				Portnum: proto.PortNum_RANGE_TEST_APP,
+				Payload: FormatPayload(seq),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send range test packet: %w", err)
+	}
+	Logger.Debug("Range test packet sent", "seq", seq)
+	return nil
+}
+
+// FormatPayload returns the payload of a range test packet, which is the text "seq <n>".
+func FormatPayload(seq uint32) []byte {
+	return strconv.AppendUint([]byte(payloadPrefix), uint64(seq), 10)
+}
+
+// ParsePayload returns the sequence number of a range test packet payload.
+func ParsePayload(payload []byte) (uint32, error) {
+	text, ok := strings.CutPrefix(strings.TrimSpace(string(payload)), payloadPrefix)
+	if !ok {
+		return 0, ErrInvalidPayload
+	}
+	seq, err := strconv.ParseUint(text, 10, 32)
+	if err != nil {
+		return 0, ErrInvalidPayload
+	}
+	return uint32(seq), nil
+}
@@ -0,0 +1,120 @@
+package rangetest
+
+import (
+	"cmp"
+	"slices"
+)
+
+// DefaultSNRLimit is the demodulation SNR floor of the default LongFast preset (SF11) in dB.
+const DefaultSNRLimit = -17.5
+
+// LinkBudget describes the radio parameters needed to compute link budget statistics.
+type LinkBudget struct {
+	// TxPower is the transmit power of the sender in dBm. Zero disables path loss statistics.
+	TxPower float64
+	// AntennaGain is the sum of sender and receiver antenna gains in dBi, minus cable losses.
+	AntennaGain float64
+	// SNRLimit is the lowest SNR the receiver can demodulate with the used spreading factor.
+	// Zero means DefaultSNRLimit.
+	SNRLimit float64
+}
+
+// Summary describes the distribution of a measured value.
+type Summary struct {
+	Count int
+	Min   float64
+	Max   float64
+	Mean  float64
+}
+
+func (s *Summary) add(value float64) {
+	if s.Count == 0 || value < s.Min {
+		s.Min = value
+	}
+	if s.Count == 0 || value > s.Max {
+		s.Max = value
+	}
+	s.Count++
+	s.Mean += (value - s.Mean) / float64(s.Count)
+}
+
+// Stats are the range test statistics of a single sender.
+type Stats struct {
+	// From is the node number of the sender.
+	From uint32
+	// FirstSeq and LastSeq are the lowest and highest received sequence numbers.
+	FirstSeq, LastSeq uint32
+	// Received is the number of unique received packets.
+	Received int
+	// Duplicates is the number of packets received more than once, e.g. via several relays.
+	Duplicates int
+	// Lost is the number of packets between FirstSeq and LastSeq which were not received.
+	Lost int
+	// LossRatio is the share of lost packets between FirstSeq and LastSeq.
+	LossRatio float64
+
+	RSSI Summary
+	SNR  Summary
+	// PathLoss is the path loss in dB of directly received packets. Empty if the transmit power is unknown.
+	PathLoss Summary
+	// SNRMargin is the SNR reserve above the demodulation limit of directly received packets.
+	// A negative minimum means some packets were received below the nominal limit.
+	SNRMargin Summary
+}
+
+// Analyze computes statistics for each sender in the records, ordered by node number.
+// If a sender restarted its sequence during the test, the records should be split before analysis.
+func Analyze(records []Record, budget LinkBudget) []Stats {
+	snrLimit := budget.SNRLimit
+	if snrLimit == 0 {
+		snrLimit = DefaultSNRLimit
+	}
+
+	type senderState struct {
+		stats Stats
+		seen  map[uint32]struct{}
+	}
+	senders := make(map[uint32]*senderState)
+	for _, record := range records {
+		state, ok := senders[record.From]
+		if !ok {
+			state = &senderState{
+				stats: Stats{From: record.From, FirstSeq: record.Seq, LastSeq: record.Seq},
+				seen:  make(map[uint32]struct{}),
+			}
+			senders[record.From] = state
+		}
+		if _, dup := state.seen[record.Seq]; dup {
+			state.stats.Duplicates++
+			continue
+		}
+		state.seen[record.Seq] = struct{}{}
+
+		stats := &state.stats
+		stats.Received++
+		stats.FirstSeq = min(stats.FirstSeq, record.Seq)
+		stats.LastSeq = max(stats.LastSeq, record.Seq)
+		stats.RSSI.add(float64(record.RSSI))
+		stats.SNR.add(float64(record.SNR))
+		if record.HopsAway > 0 {
+			continue // the signal was measured from the last relay, not from the sender
+		}
+		stats.SNRMargin.add(float64(record.SNR) - snrLimit)
+		if budget.TxPower != 0 {
+			stats.PathLoss.add(budget.TxPower + budget.AntennaGain - float64(record.RSSI))
+		}
+	}
+
+	result := make([]Stats, 0, len(senders))
+	for _, state := range senders {
+		stats := state.stats
+		expected := int(stats.LastSeq-stats.FirstSeq) + 1
+		stats.Lost = expected - stats.Received
+		stats.LossRatio = float64(stats.Lost) / float64(expected)
+		result = append(result, stats)
+	}
+	slices.SortFunc(result, func(a, b Stats) int {
+		return cmp.Compare(a.From, b.From)
+	})
+	return result
+}
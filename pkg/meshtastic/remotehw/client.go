@@ -0,0 +1,336 @@
+// Package remotehw controls GPIO pins of remote nodes through the remote hardware module
+// on REMOTE_HARDWARE_APP.
+package remotehw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/admin"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const defaultTimeout = 30 * time.Second
+
+var (
+	// ErrPinNotAllowed is returned when a mask includes pins the node does not advertise for the operation.
+	ErrPinNotAllowed = errors.New("pin is not available for remote control")
+	// ErrStopped is returned by Read and Watch after Run returned.
+	ErrStopped = errors.New("client is stopped")
+)
+
+// Event is a change of watched GPIO pins reported by the node.
+type Event struct {
+	// Mask selects the pins included in Value.
+	Mask uint64
+	// Value holds the current levels of the pins.
+	Value uint64
+	// Received is the time the event was received.
+	Received time.Time
+}
+
+// Client reads, writes and watches GPIO pins of a single remote node.
+// Run must be active for Read and Watch to receive replies.
+type Client struct {
+	// Transport is used to exchange packets with the mesh, usually a Device.
+	Transport meshtastic.MeshTransport
+	// Node is the node number of the remote node.
+	Node uint32
+	// ChannelIndex is the channel used for remote hardware messages. Firmware only accepts
+	// them on a private channel, traditionally named "gpio".
+	ChannelIndex uint32
+	// Pins lists GPIO pins advertised by the node. If empty, masks are not validated.
+	Pins []*proto.RemoteHardwarePin
+	// Timeout is the time to wait for a reply. Defaults to 30 seconds.
+	Timeout time.Duration
+
+	lock sync.Mutex
+	// reads holds pending reads by the ID of the request packet
+	reads    map[uint32]pendingRead
+	watchers map[chan Event]uint64
+	// done is closed when Run returns
+	done    chan struct{}
+	stopped bool
+}
+
+// LoadPins fetches pins the node advertises for remote control using an admin client
+// of the local node.
+func (c *Client) LoadPins(ctx context.Context, adminClient *admin.Client) error {
+	nodePins, err := adminClient.GetNodeRemoteHardwarePins(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get remote hardware pins: %w", err)
+	}
+
+	var pins []*proto.RemoteHardwarePin
+	for _, nodePin := range nodePins {
+		if nodePin.GetNodeNum() == c.Node && nodePin.GetPin() != nil {
+			pins = append(pins, nodePin.GetPin())
+		}
+	}
+	c.Pins = pins
+	return nil
+}
+
+// Run receives replies and change events from the node until the context is cancelled
+// or the transport fails.
+func (c *Client) Run(ctx context.Context) error {
+	c.lock.Lock()
+	if c.stopped {
+		c.done, c.stopped = nil, false
+	}
+	c.lock.Unlock()
+	defer c.stop()
+
+	for {
+		packet, err := c.Transport.ReceiveFromMesh(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		decoded := packet.GetDecoded()
+		if packet.GetFrom() != c.Node || decoded.GetPortnum() != proto.PortNum_REMOTE_HARDWARE_APP {
+			continue
+		}
+		msg := new(proto.HardwareMessage)
+		if err = protobuf.Unmarshal(decoded.GetPayload(), msg); err != nil {
+			Logger.Debug("Invalid hardware message", "from", meshtastic.FormatNodeID(packet.GetFrom()))
+			continue
+		}
+		c.dispatch(msg, decoded.GetRequestId())
+	}
+}
+
+type pendingRead struct {
+	mask  uint64
+	reply chan Event
+}
+
+// Read reads the levels of the pins selected by the mask.
+func (c *Client) Read(ctx context.Context, mask uint64) (uint64, error) {
+	if err := c.validate(mask, proto.RemoteHardwarePinType_DIGITAL_READ, proto.RemoteHardwarePinType_DIGITAL_WRITE); err != nil {
+		return 0, err
+	}
+
+	// the ID is assigned here to match the reply, so a late reply to a timed out read
+	// is not taken for the reply to the next one
+	id := rand.Uint32() | 1
+	reply := make(chan Event, 1)
+	c.lock.Lock()
+	if c.stopped {
+		c.lock.Unlock()
+		return 0, ErrStopped
+	}
+	done := c.doneChan()
+	if c.reads == nil {
+		c.reads = make(map[uint32]pendingRead)
+	}
+	c.reads[id] = pendingRead{mask: mask, reply: reply}
+	c.lock.Unlock()
+	defer c.removeRead(id)
+
+	err := c.send(ctx, id, &proto.HardwareMessage{Type: proto.HardwareMessage_READ_GPIOS, GpioMask: mask}, true)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-done:
+		return 0, ErrStopped
+	case event := <-reply:
+		return event.Value & mask, nil
+	}
+}
+
+// Write sets the levels of the pins selected by the mask to the bits of the value.
+func (c *Client) Write(ctx context.Context, mask, value uint64) error {
+	if err := c.validate(mask, proto.RemoteHardwarePinType_DIGITAL_WRITE); err != nil {
+		return err
+	}
+	return c.send(ctx, 0, &proto.HardwareMessage{
+		Type:      proto.HardwareMessage_WRITE_GPIOS,
+		GpioMask:  mask,
+		GpioValue: value,
+	}, false)
+}
+
+// Watch asks the node to report changes of the pins selected by the mask. Events are delivered
+// to the returned channel until the context is cancelled, the returned stop function is called
+// or Run returns. The channel is closed then. It returns ErrStopped after Run returned.
+func (c *Client) Watch(ctx context.Context, mask uint64) (<-chan Event, func(), error) {
+	if err := c.validate(mask, proto.RemoteHardwarePinType_DIGITAL_READ, proto.RemoteHardwarePinType_DIGITAL_WRITE); err != nil {
+		return nil, nil, err
+	}
+
+	events := make(chan Event, 16)
+	c.lock.Lock()
+	if c.stopped {
+		c.lock.Unlock()
+		return nil, nil, ErrStopped
+	}
+	done := c.doneChan()
+	if c.watchers == nil {
+		c.watchers = make(map[chan Event]uint64)
+	}
+	c.watchers[events] = mask
+	c.lock.Unlock()
+
+	if err := c.send(ctx, 0, &proto.HardwareMessage{Type: proto.HardwareMessage_WATCH_GPIOS, GpioMask: mask}, false); err != nil {
+		c.removeWatcher(events)
+		return nil, nil, err
+	}
+
+	stopped := make(chan struct{})
+	var stopOnce sync.Once
+	stop := func() {
+		stopOnce.Do(func() { close(stopped) })
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+		case <-done:
+		}
+		c.removeWatcher(events)
+	}()
+	return events, stop, nil
+}
+
+func (c *Client) dispatch(msg *proto.HardwareMessage, requestID uint32) {
+	event := Event{Mask: msg.GetGpioMask(), Value: msg.GetGpioValue(), Received: time.Now()}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	switch msg.GetType() {
+	case proto.HardwareMessage_READ_GPIOS_REPLY:
+		read, ok := c.reads[requestID]
+		if !ok && requestID == 0 {
+			// replies without a request ID are matched by the mask
+			for id, pending := range c.reads {
+				if pending.mask == event.Mask {
+					read, ok, requestID = pending, true, id
+					break
+				}
+			}
+		}
+		if !ok {
+			Logger.Debug("Stale GPIO read reply dropped", "request", requestID)
+			return
+		}
+		read.reply <- event
+		delete(c.reads, requestID)
+	case proto.HardwareMessage_GPIOS_CHANGED:
+		for events, mask := range c.watchers {
+			if event.Mask != 0 && event.Mask&mask == 0 {
+				continue
+			}
+			select {
+			case events <- event:
+			default:
+				Logger.Warn("Watcher is not keeping up. GPIO event dropped")
+			}
+		}
+	}
+}
+
+// send sends the message in a packet with the given ID. Zero ID is assigned by the transport.
+func (c *Client) send(ctx context.Context, id uint32, msg *proto.HardwareMessage, wantResponse bool) error {
+	payload, err := protobuf.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshalling error: %w", err)
+	}
+	err = c.Transport.SendToMesh(ctx, &proto.MeshPacket{
+		Id:      id,
+		To:      c.Node,
+		Channel: c.ChannelIndex,
+		PayloadVariant: &proto.MeshPacket_Decoded{
+			Decoded: &proto.Data{
+				Portnum:      proto.PortNum_REMOTE_HARDWARE_APP,
+				Payload:      payload,
+				WantResponse: wantResponse,
+			},
+		},
+		WantAck: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send hardware message: %w", err)
+	}
+	return nil
+}
+
+// validate checks that every pin in the mask is advertised with one of the allowed types.
+func (c *Client) validate(mask uint64, allowed ...proto.RemoteHardwarePinType) error {
+	if mask == 0 {
+		return errors.New("empty pin mask")
+	}
+	if len(c.Pins) == 0 {
+		return nil
+	}
+
+	var permitted uint64
+	for _, pin := range c.Pins {
+		for _, pinType := range allowed {
+			if pin.GetType() == pinType && pin.GetGpioPin() < 64 {
+				permitted |= 1 << pin.GetGpioPin()
+			}
+		}
+	}
+	if denied := mask &^ permitted; denied != 0 {
+		return fmt.Errorf("%w: mask %#x", ErrPinNotAllowed, denied)
+	}
+	return nil
+}
+
+func (c *Client) removeRead(id uint32) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.reads, id)
+}
+
+func (c *Client) removeWatcher(events chan Event) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.watchers[events]; ok {
+		delete(c.watchers, events)
+		close(events)
+	}
+}
+
+// stop closes the watcher channels and releases everyone waiting for Run.
+func (c *Client) stop() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for events := range c.watchers {
+		close(events)
+	}
+	c.watchers = nil
+	close(c.doneChan())
+	c.stopped = true
+}
+
+// doneChan returns the channel closed when Run returns. The lock must be held.
+func (c *Client) doneChan() chan struct{} {
+	if c.done == nil {
+		c.done = make(chan struct{})
+	}
+	return c.done
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return c.Timeout
+}
@@ -0,0 +1,339 @@
+// Package reticulum carries Reticulum frames over the mesh on RETICULUM_TUNNEL_APP. Frames are
+// exchanged with rnsd through HDLC framed streams, which Reticulum TCP client and pipe
+// interfaces use.
+package reticulum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	// MTU is the Reticulum link MTU.
+	MTU = 500
+	// DefaultMaxChannelUtilization is the channel utilization in percent above which sending pauses.
+	DefaultMaxChannelUtilization = 25
+
+	// Every fragment starts with the frame ID and a byte with the fragment index
+	// in the high nibble and the number of fragments in the low nibble.
+	headerSize      = 2
+	maxFragment     = int(proto.Constants_DATA_PAYLOAD_LEN) - headerSize
+	maxFragments    = 15
+	maxFrameSize    = maxFragment * maxFragments
+	defaultQueue    = 32
+	reassemblyLimit = 30 * time.Second
+	utilizationPoll = time.Second
+)
+
+// Endpoint connects Reticulum interfaces to the mesh. Frames received from any attached stream
+// are broadcast to the mesh, and frames received from the mesh are written to all streams.
+type Endpoint struct {
+	// Mesh is used to exchange packets with other nodes.
+	Mesh meshtastic.MeshTransport
+	// LocalNode is the node number of the local node. Its device metrics report the channel utilization.
+	LocalNode uint32
+	// ChannelIndex is the channel used for Reticulum packets.
+	ChannelIndex uint32
+	// MaxChannelUtilization pauses sending while the channel utilization in percent is higher.
+	// Defaults to DefaultMaxChannelUtilization. Set it to 100 to disable the limit.
+	MaxChannelUtilization float32
+	// QueueSize is the number of frames waiting to be sent before the oldest are dropped.
+	// Defaults to 32.
+	QueueSize int
+
+	init        sync.Once
+	queue       chan []byte
+	lock        sync.Mutex
+	streams     map[io.Writer]chan []byte
+	utilization float32
+	nextID      byte
+}
+
+// Run exchanges frames with the mesh until the context is cancelled or the mesh fails.
+func (e *Endpoint) Run(ctx context.Context) error {
+	e.setup()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, 2)
+	go func() { errs <- e.send(ctx) }()
+	go func() { errs <- e.receive(ctx) }()
+	return <-errs
+}
+
+// Attach exchanges frames with a stream, like a connection from rnsd or the standard streams of
+// a pipe interface command, until the context is cancelled or reading fails.
+func (e *Endpoint) Attach(ctx context.Context, stream io.ReadWriter) error {
+	e.setup()
+	outgoing := make(chan []byte, defaultQueue)
+	e.lock.Lock()
+	e.streams[stream] = outgoing
+	e.lock.Unlock()
+	defer func() {
+		e.lock.Lock()
+		delete(e.streams, stream)
+		e.lock.Unlock()
+		close(outgoing)
+	}()
+
+	go func() {
+		for encoded := range outgoing {
+			if _, err := stream.Write(encoded); err != nil {
+				Logger.Debug("Failed to write reticulum frame", "error", err)
+			}
+		}
+	}()
+
+	reader := NewHDLCReader(stream, maxFrameSize)
+	for {
+		frame, err := reader.ReadFrame()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ErrFrameTooLarge) {
+			Logger.Warn("Reticulum frame is too large for the mesh")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		e.enqueue(frame)
+	}
+}
+
+// ListenAndServe accepts connections of Reticulum TCP client interfaces on the address
+// until the context is cancelled.
+func (e *Endpoint) ListenAndServe(ctx context.Context, address string) error {
+	listener, err := new(net.ListenConfig).Listen(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		Logger.Info("Reticulum interface connected", "remote", conn.RemoteAddr())
+		go func() {
+			defer conn.Close()
+			err := e.Attach(ctx, conn)
+			Logger.Info("Reticulum interface disconnected", "remote", conn.RemoteAddr(), "error", err)
+		}()
+	}
+}
+
+// ChannelUtilization returns the last known channel utilization of the local node in percent.
+func (e *Endpoint) ChannelUtilization() float32 {
+	e.setup()
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.utilization
+}
+
+// SetChannelUtilization updates the channel utilization used for rate limiting. It is called
+// automatically for device metrics of the local node.
+func (e *Endpoint) SetChannelUtilization(percent float32) {
+	e.setup()
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.utilization = percent
+}
+
+func (e *Endpoint) setup() {
+	e.init.Do(func() {
+		size := e.QueueSize
+		if size <= 0 {
+			size = defaultQueue
+		}
+		e.queue = make(chan []byte, size)
+		e.streams = make(map[io.Writer]chan []byte)
+	})
+}
+
+// enqueue queues a frame for sending, dropping the oldest frame if the queue is full.
+func (e *Endpoint) enqueue(frame []byte) {
+	for {
+		select {
+		case e.queue <- frame:
+			return
+		default:
+		}
+		select {
+		case <-e.queue:
+			Logger.Warn("Reticulum send queue is full. Frame dropped")
+		default:
+		}
+	}
+}
+
+// send broadcasts queued frames while the channel utilization allows it.
+func (e *Endpoint) send(ctx context.Context) error {
+	limit := e.MaxChannelUtilization
+	if limit <= 0 {
+		limit = DefaultMaxChannelUtilization
+	}
+
+	for {
+		var frame []byte
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case frame = <-e.queue:
+		}
+
+		for e.ChannelUtilization() > limit {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(utilizationPoll):
+			}
+		}
+
+		e.nextID++
+		for _, payload := range fragment(e.nextID, frame) {
+			err := e.Mesh.SendToMesh(ctx, &proto.MeshPacket{
+				To:      meshtastic.BroadcastNodenum,
+				Channel: e.ChannelIndex,
+				PayloadVariant: &proto.MeshPacket_Decoded{
+					Decoded: &proto.Data{
+						Portnum: proto.PortNum_RETICULUM_TUNNEL_APP,
+						Payload: payload,
+					},
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to send reticulum packet: %w", err)
+			}
+		}
+	}
+}
+
+// receive writes frames received from the mesh to attached streams.
+func (e *Endpoint) receive(ctx context.Context) error {
+	fragments := make(map[uint64]*partialFrame)
+	for {
+		packet, err := e.Mesh.ReceiveFromMesh(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		decoded := packet.GetDecoded()
+		switch decoded.GetPortnum() {
+		case proto.PortNum_TELEMETRY_APP:
+			if packet.GetFrom() == e.LocalNode {
+				e.handleTelemetry(decoded.GetPayload())
+			}
+		case proto.PortNum_RETICULUM_TUNNEL_APP:
+			if packet.GetFrom() == e.LocalNode {
+				continue
+			}
+			frame := reassemble(fragments, packet.GetFrom(), decoded.GetPayload(), time.Now())
+			if frame != nil {
+				e.broadcast(frame)
+			}
+		}
+	}
+}
+
+func (e *Endpoint) handleTelemetry(payload []byte) {
+	telemetry := new(proto.Telemetry)
+	if protobuf.Unmarshal(payload, telemetry) != nil {
+		return
+	}
+	if metrics := telemetry.GetDeviceMetrics(); metrics != nil && metrics.ChannelUtilization != nil {
+		e.SetChannelUtilization(metrics.GetChannelUtilization())
+	}
+}
+
+func (e *Endpoint) broadcast(frame []byte) {
+	encoded := EncodeHDLC(frame)
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, outgoing := range e.streams {
+		select {
+		case outgoing <- encoded:
+		default:
+			Logger.Warn("Reticulum interface is not keeping up. Frame dropped")
+		}
+	}
+}
+
+func fragment(id byte, frame []byte) [][]byte {
+	count := max((len(frame)+maxFragment-1)/maxFragment, 1)
+	fragments := make([][]byte, 0, count)
+	for i := range count {
+		chunk := frame[i*maxFragment : min((i+1)*maxFragment, len(frame))]
+		payload := make([]byte, headerSize, headerSize+len(chunk))
+		payload[0] = id
+		payload[1] = byte(i)<<4 | byte(count)
+		fragments = append(fragments, append(payload, chunk...))
+	}
+	return fragments
+}
+
+type partialFrame struct {
+	fragments [][]byte
+	missing   int
+	started   time.Time
+}
+
+// reassemble stores a fragment and returns the frame once all its fragments are received.
+func reassemble(frames map[uint64]*partialFrame, from uint32, payload []byte, now time.Time) []byte {
+	if len(payload) < headerSize {
+		return nil
+	}
+	index, count := int(payload[1]>>4), int(payload[1]&0x0f)
+	if count == 0 || index >= count {
+		return nil
+	}
+	if count == 1 {
+		return payload[headerSize:]
+	}
+
+	for key, frame := range frames {
+		if now.Sub(frame.started) > reassemblyLimit {
+			delete(frames, key)
+		}
+	}
+
+	key := uint64(from)<<8 | uint64(payload[0])
+	frame, ok := frames[key]
+	if !ok || len(frame.fragments) != count {
+		frame = &partialFrame{fragments: make([][]byte, count), missing: count, started: now}
+		frames[key] = frame
+	}
+	if frame.fragments[index] == nil {
+		frame.fragments[index] = payload[headerSize:]
+		frame.missing--
+	}
+	if frame.missing > 0 {
+		return nil
+	}
+	delete(frames, key)
+
+	var result []byte
+	for _, part := range frame.fragments {
+		result = append(result, part...)
+	}
+	return result
+}
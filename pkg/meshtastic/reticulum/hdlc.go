@@ -0,0 +1,84 @@
+package reticulum
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// HDLC-like framing used by Reticulum TCP and pipe interfaces.
+const (
+	hdlcFlag    = 0x7e
+	hdlcEscape  = 0x7d
+	hdlcEscMask = 0x20
+)
+
+// ErrFrameTooLarge is returned when a frame exceeds the maximum frame size.
+var ErrFrameTooLarge = errors.New("frame is too large")
+
+// EncodeHDLC returns the frame escaped and enclosed in flag bytes.
+func EncodeHDLC(frame []byte) []byte {
+	encoded := make([]byte, 0, len(frame)+len(frame)/8+2)
+	encoded = append(encoded, hdlcFlag)
+	for _, b := range frame {
+		if b == hdlcFlag || b == hdlcEscape {
+			encoded = append(encoded, hdlcEscape, b^hdlcEscMask)
+		} else {
+			encoded = append(encoded, b)
+		}
+	}
+	return append(encoded, hdlcFlag)
+}
+
+// HDLCReader reads HDLC framed frames from a stream.
+type HDLCReader struct {
+	r       *bufio.Reader
+	maxSize int
+}
+
+// NewHDLCReader creates a reader of frames not larger than maxSize bytes.
+func NewHDLCReader(r io.Reader, maxSize int) *HDLCReader {
+	return &HDLCReader{r: bufio.NewReader(r), maxSize: maxSize}
+}
+
+// ReadFrame returns the next non-empty frame. Oversized frames are skipped and reported
+// with ErrFrameTooLarge.
+func (h *HDLCReader) ReadFrame() ([]byte, error) {
+	var (
+		frame    []byte
+		inFrame  bool
+		escaped  bool
+		oversize bool
+	)
+	for {
+		b, err := h.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case b == hdlcFlag:
+			if oversize {
+				return nil, ErrFrameTooLarge
+			}
+			if inFrame && len(frame) > 0 {
+				return frame, nil
+			}
+			inFrame, escaped, frame = true, false, frame[:0]
+		case !inFrame:
+			// bytes outside of a frame are noise
+		case b == hdlcEscape:
+			escaped = true
+		default:
+			if escaped {
+				b ^= hdlcEscMask
+				escaped = false
+			}
+			if len(frame) >= h.maxSize {
+				oversize = true
+				continue
+			}
+			frame = append(frame, b)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package meshtastic
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// ErrRouterStopped is returned by routed transports after the router has stopped reading frames.
+var ErrRouterStopped = errors.New("frame router is stopped")
+
+const defaultRouteBufferSize = 100
+
+// FrameMatchFunc reports whether a frame received from the radio should be delivered to a route.
+type FrameMatchFunc func(frame *proto.FromRadio) bool
+
+// MatchAllFrames matches every frame.
+func MatchAllFrames(*proto.FromRadio) bool {
+	return true
+}
+
+// MatchPackets matches frames carrying mesh packets.
+func MatchPackets(frame *proto.FromRadio) bool {
+	return frame.GetPacket() != nil
+}
+
+// MatchClientNotifications matches frames carrying client notifications.
+func MatchClientNotifications(frame *proto.FromRadio) bool {
+	return frame.GetClientNotification() != nil
+}
+
+// MatchLogRecords matches frames carrying firmware log records.
+func MatchLogRecords(frame *proto.FromRadio) bool {
+	return frame.GetLogRecord() != nil
+}
+
+// MatchPortNums matches frames carrying decoded mesh packets for one of the given ports.
+func MatchPortNums(ports ...proto.PortNum) FrameMatchFunc {
+	return func(frame *proto.FromRadio) bool {
+		decoded := frame.GetPacket().GetDecoded()
+		return decoded != nil && slices.Contains(ports, decoded.GetPortnum())
+	}
+}
+
+// NewFrameRouter creates a router reading frames from the given transport.
+func NewFrameRouter(transport HardwareTransport) *FrameRouter {
+	return &FrameRouter{transport: transport}
+}
+
+// FrameRouter shares a single HardwareTransport between several consumers.
+//
+// A radio connection is a single stream of frames, while each consumer (a Device, an MQTT proxy,
+// a file transfer client, etc.) reads it in a loop and ignores frames it does not understand.
+// The router reads the stream once and hands every frame to all routes whose match function
+// accepts it. Each route is itself a HardwareTransport, so existing consumers work unchanged.
+type FrameRouter struct {
+	// BufferSize is the number of frames queued per route. When a route's queue is full,
+	// the oldest frame is dropped.
+	BufferSize int
+
+	transport HardwareTransport
+	lock      sync.Mutex
+	routes    []*routedTransport
+	stopped   bool
+}
+
+// Route creates a new transport receiving frames accepted by the match function.
+// Frames sent through the route are passed directly to the underlying transport.
+func (r *FrameRouter) Route(match FrameMatchFunc) HardwareTransport {
+	bufferSize := r.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultRouteBufferSize
+	}
+
+	route := &routedTransport{
+		router: r,
+		match:  match,
+		frames: make(chan *proto.FromRadio, bufferSize),
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.stopped {
+		close(route.frames)
+	} else {
+		r.routes = append(r.routes, route)
+	}
+	return route
+}
+
+// Run reads frames from the transport and distributes them until the context is cancelled
+// or the transport returns an error. After Run returns, all routes report ErrRouterStopped.
+func (r *FrameRouter) Run(ctx context.Context) error {
+	defer r.stop()
+	for {
+		frame, err := r.transport.ReceiveFromRadio(ctx)
+		if errors.Is(err, ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		r.dispatch(frame)
+	}
+}
+
+func (r *FrameRouter) dispatch(frame *proto.FromRadio) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, route := range r.routes {
+		if !route.match(frame) {
+			continue
+		}
+		select {
+		case route.frames <- frame:
+		default:
+			select { // drop the oldest frame
+			case <-route.frames:
+			default:
+			}
+			select {
+			case route.frames <- frame:
+			default:
+			}
+		}
+	}
+}
+
+func (r *FrameRouter) stop() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.stopped = true
+	for _, route := range r.routes {
+		close(route.frames)
+	}
+	r.routes = nil
+}
+
+// routedTransport is a view of the router's transport restricted to matching frames.
+type routedTransport struct {
+	router *FrameRouter
+	match  FrameMatchFunc
+	frames chan *proto.FromRadio
+}
+
+func (t *routedTransport) SendToRadio(ctx context.Context, packet *proto.ToRadio) error {
+	return t.router.transport.SendToRadio(ctx, packet)
+}
+
+func (t *routedTransport) ReceiveFromRadio(ctx context.Context) (*proto.FromRadio, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case frame, ok := <-t.frames:
+		if !ok {
+			return nil, ErrRouterStopped
+		}
+		return frame, nil
+	}
+}
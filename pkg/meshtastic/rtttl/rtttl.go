@@ -0,0 +1,199 @@
+// Package rtttl parses and renders RTTTL (Ring Tone Text Transfer Language) melodies, used
+// by the external notification module for ringtones.
+package rtttl
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDuration = 4
+	defaultOctave   = 6
+	defaultBPM      = 63
+	minOctave       = 4
+	maxOctave       = 7
+	minBPM          = 25
+	maxBPM          = 900
+)
+
+// ErrSyntax is returned for melodies which do not follow the RTTTL format.
+var ErrSyntax = errors.New("invalid RTTTL")
+
+// semitones are note offsets from C within an octave. B is also written as H.
+var semitones = map[byte]int{'c': 0, 'd': 2, 'e': 4, 'f': 5, 'g': 7, 'a': 9, 'b': 11, 'h': 11}
+
+// Note is a tone of a melody.
+type Note struct {
+	// Frequency is the tone frequency in Hz. Zero means a pause.
+	Frequency float64
+	// Duration is the length of the tone.
+	Duration time.Duration
+}
+
+// Melody is a parsed RTTTL melody.
+type Melody struct {
+	Name string
+	// Duration, Octave and BPM are the defaults of the melody.
+	Duration int
+	Octave   int
+	BPM      int
+	Notes    []Note
+}
+
+// Length returns the total playing time of the melody.
+func (m *Melody) Length() time.Duration {
+	var total time.Duration
+	for _, note := range m.Notes {
+		total += note.Duration
+	}
+	return total
+}
+
+// Validate reports whether the melody is valid RTTTL.
+func Validate(text string) error {
+	_, err := Parse(text)
+	return err
+}
+
+// Parse parses an RTTTL melody in the "name:d=4,o=5,b=120:8c,8e,4g" form.
+func Parse(text string) (*Melody, error) {
+	parts := strings.SplitN(strings.TrimSpace(text), ":", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected name, defaults and notes separated by ':'", ErrSyntax)
+	}
+
+	melody := &Melody{
+		Name:     strings.TrimSpace(parts[0]),
+		Duration: defaultDuration,
+		Octave:   defaultOctave,
+		BPM:      defaultBPM,
+	}
+	if err := melody.parseDefaults(parts[1]); err != nil {
+		return nil, err
+	}
+
+	wholeNote := 4 * time.Minute / time.Duration(melody.BPM)
+	for i, token := range strings.Split(parts[2], ",") {
+		note, err := melody.parseNote(strings.ToLower(strings.TrimSpace(token)), wholeNote)
+		if err != nil {
+			return nil, fmt.Errorf("%w: note %d %q: %w", ErrSyntax, i+1, token, err)
+		}
+		melody.Notes = append(melody.Notes, note)
+	}
+	return melody, nil
+}
+
+func (m *Melody) parseDefaults(defaults string) error {
+	for _, setting := range strings.Split(defaults, ",") {
+		setting = strings.TrimSpace(setting)
+		if setting == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return fmt.Errorf("%w: default %q", ErrSyntax, setting)
+		}
+		number, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%w: default %q", ErrSyntax, setting)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "d":
+			if !validDuration(number) {
+				return fmt.Errorf("%w: duration %d", ErrSyntax, number)
+			}
+			m.Duration = number
+		case "o":
+			if number < minOctave || number > maxOctave {
+				return fmt.Errorf("%w: octave %d", ErrSyntax, number)
+			}
+			m.Octave = number
+		case "b":
+			if number < minBPM || number > maxBPM {
+				return fmt.Errorf("%w: tempo %d", ErrSyntax, number)
+			}
+			m.BPM = number
+		default:
+			return fmt.Errorf("%w: unknown default %q", ErrSyntax, key)
+		}
+	}
+	return nil
+}
+
+// parseNote parses a note in the [duration]note[#][.][octave][.] form.
+func (m *Melody) parseNote(token string, wholeNote time.Duration) (Note, error) {
+	i := 0
+	for i < len(token) && token[i] >= '0' && token[i] <= '9' {
+		i++
+	}
+	duration := m.Duration
+	if i > 0 {
+		duration, _ = strconv.Atoi(token[:i])
+		if !validDuration(duration) {
+			return Note{}, errors.New("invalid duration")
+		}
+	}
+	if i >= len(token) {
+		return Note{}, errors.New("missing note")
+	}
+
+	letter := token[i]
+	i++
+	semitone, isTone := semitones[letter]
+	if !isTone && letter != 'p' {
+		return Note{}, errors.New("invalid note")
+	}
+	if i < len(token) && token[i] == '#' {
+		if !isTone {
+			return Note{}, errors.New("sharp pause")
+		}
+		semitone++
+		i++
+	}
+
+	dotted := false
+	if i < len(token) && token[i] == '.' {
+		dotted = true
+		i++
+	}
+	octave := m.Octave
+	if i < len(token) && token[i] >= '0' && token[i] <= '9' {
+		octave = int(token[i] - '0')
+		if octave < minOctave || octave > maxOctave {
+			return Note{}, errors.New("invalid octave")
+		}
+		i++
+	}
+	if i < len(token) && token[i] == '.' {
+		dotted = true
+		i++
+	}
+	if i != len(token) {
+		return Note{}, errors.New("unexpected characters")
+	}
+
+	note := Note{Duration: wholeNote / time.Duration(duration)}
+	if dotted {
+		note.Duration += note.Duration / 2
+	}
+	if isTone {
+		// A4 is 440 Hz, every semitone is a twelfth of an octave
+		note.Frequency = 440 * math.Exp2(float64(octave-4)+float64(semitone-9)/12)
+	}
+	return note, nil
+}
+
+func validDuration(d int) bool {
+	switch d {
+	case 1, 2, 4, 8, 16, 32:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,88 @@
+package rtttl
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+// DefaultSampleRate is the sample rate used when WAVOptions.SampleRate is not set.
+const DefaultSampleRate = 22050
+
+// WAVOptions control rendering of a melody.
+type WAVOptions struct {
+	// SampleRate in Hz. Defaults to DefaultSampleRate.
+	SampleRate int
+	// Volume from 0 to 1. Defaults to 0.5.
+	Volume float64
+	// Gap is silence inserted at the end of each note to separate repeated notes,
+	// like buzzers do. Defaults to 10 ms.
+	Gap time.Duration
+}
+
+// WriteWAV renders the melody as a mono 16-bit PCM WAV file with a square wave,
+// which sounds close to a piezo buzzer.
+func WriteWAV(w io.Writer, melody *Melody, opts WAVOptions) error {
+	if opts.SampleRate <= 0 {
+		opts.SampleRate = DefaultSampleRate
+	}
+	if opts.Volume <= 0 || opts.Volume > 1 {
+		opts.Volume = 0.5
+	}
+	if opts.Gap <= 0 {
+		opts.Gap = 10 * time.Millisecond
+	}
+
+	samplesOf := func(d time.Duration) int {
+		return int(d.Seconds() * float64(opts.SampleRate))
+	}
+	total := 0
+	for _, note := range melody.Notes {
+		total += samplesOf(note.Duration)
+	}
+
+	const bytesPerSample = 2
+	dataSize := uint32(total * bytesPerSample)
+	header := make([]byte, 0, 44)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, 36+dataSize)
+	header = append(header, "WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, 16) // format chunk size
+	header = binary.LittleEndian.AppendUint16(header, 1)  // PCM
+	header = binary.LittleEndian.AppendUint16(header, 1)  // mono
+	header = binary.LittleEndian.AppendUint32(header, uint32(opts.SampleRate))
+	header = binary.LittleEndian.AppendUint32(header, uint32(opts.SampleRate*bytesPerSample))
+	header = binary.LittleEndian.AppendUint16(header, bytesPerSample)
+	header = binary.LittleEndian.AppendUint16(header, 8*bytesPerSample)
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, dataSize)
+
+	out := bufio.NewWriter(w)
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+
+	amplitude := int16(opts.Volume * math.MaxInt16)
+	gap := samplesOf(opts.Gap)
+	sample := make([]byte, bytesPerSample)
+	for _, note := range melody.Notes {
+		count := samplesOf(note.Duration)
+		for i := range count {
+			var value int16
+			if note.Frequency > 0 && i < count-gap {
+				phase := math.Mod(float64(i)*note.Frequency/float64(opts.SampleRate), 1)
+				value = amplitude
+				if phase >= 0.5 {
+					value = -amplitude
+				}
+			}
+			binary.LittleEndian.PutUint16(sample, uint16(value))
+			if _, err := out.Write(sample); err != nil {
+				return err
+			}
+		}
+	}
+	return out.Flush()
+}
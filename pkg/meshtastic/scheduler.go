@@ -0,0 +1,341 @@
+package meshtastic
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+const (
+	// DefaultMaxQueued is the default number of packets queued by a TxScheduler.
+	DefaultMaxQueued = 64
+	// DefaultStatusTimeout is the default time a TxScheduler waits for the queue status
+	// of a sent packet.
+	DefaultStatusTimeout = 2 * time.Second
+)
+
+// QueueError is returned when the radio refuses to queue a packet for transmission.
+type QueueError struct {
+	// Res is the firmware error code.
+	Res int32
+	// Message is the text of the client notification the firmware sent about the packet,
+	// such as the time until the duty cycle allows sending again. Empty if none.
+	Message string
+}
+
+func (e QueueError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("radio refused to queue packet: error %d: %s", e.Res, e.Message)
+	}
+	return fmt.Sprintf("radio refused to queue packet: error %d", e.Res)
+}
+
+// NewTxScheduler creates a scheduler sending packets through the given transport.
+func NewTxScheduler(transport HardwareTransport) *TxScheduler {
+	return &TxScheduler{
+		transport: transport,
+		free:      -1,
+		changed:   make(chan struct{}),
+	}
+}
+
+// TxScheduler is a HardwareTransport which keeps the transmit queue of the radio from
+// overflowing.
+//
+// The radio reports free slots of its transmit queue with a QueueStatus after every packet.
+// The scheduler sends packets one at a time, holds them locally while the radio queue is
+// full, and sends the ones with the highest MeshPacket_Priority first. SendToRadio blocks
+// until the radio accepted the packet and returns a QueueError if it refused it.
+//
+// Queue status is learned from received frames, so frames must be read continuously,
+// for example by a FrameRouter, and Run must be running.
+type TxScheduler struct {
+	// MaxQueued is the number of packets held locally. SendToRadio blocks while the queue
+	// is full. Defaults to DefaultMaxQueued.
+	MaxQueued int
+	// StatusTimeout is the time to wait for the queue status of a sent packet. Firmware
+	// without queue status reports is assumed to accept packets after it. Defaults to
+	// DefaultStatusTimeout.
+	StatusTimeout time.Duration
+
+	transport HardwareTransport
+	lock      sync.Mutex
+	queue     txQueue
+	seq       uint64
+	inflight  *txItem
+	// free is the number of free slots in the radio queue, -1 if not known yet
+	free    int
+	maxLen  int
+	changed chan struct{}
+}
+
+var _ HardwareTransport = &TxScheduler{}
+
+// SendToRadio queues packets for transmission and waits until the radio accepts them.
+// Other frames are sent directly.
+func (s *TxScheduler) SendToRadio(ctx context.Context, frame *proto.ToRadio) error {
+	packet := frame.GetPacket()
+	if packet == nil {
+		return s.transport.SendToRadio(ctx, frame)
+	}
+
+	item := &txItem{frame: frame, priority: txPriority(packet), done: make(chan error, 1)}
+	if err := s.push(ctx, item); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-item.done:
+		return err
+	case <-ctx.Done():
+		s.lock.Lock()
+		if item.index >= 0 {
+			heap.Remove(&s.queue, item.index)
+			s.notify()
+		}
+		s.lock.Unlock()
+		return ctx.Err()
+	}
+}
+
+// ReceiveFromRadio receives frames from the transport, tracking the queue status.
+// Client notifications about the packet being sent are attached to its QueueError,
+// and returned like all other frames, so the caller handles them too.
+func (s *TxScheduler) ReceiveFromRadio(ctx context.Context) (*proto.FromRadio, error) {
+	frame, err := s.transport.ReceiveFromRadio(ctx)
+	if err != nil {
+		return frame, err
+	}
+	if status := frame.GetQueueStatus(); status != nil {
+		s.handleStatus(status)
+	}
+	if notification := frame.GetClientNotification(); notification != nil {
+		s.handleNotification(notification)
+	}
+	return frame, nil
+}
+
+// QueueStatus returns the free and total slots of the radio transmit queue as last reported.
+// Free is -1 until the radio reports it.
+func (s *TxScheduler) QueueStatus() (free, maxLen int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.free, s.maxLen
+}
+
+// Queued returns the number of packets waiting to be sent to the radio.
+func (s *TxScheduler) Queued() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.queue.Len()
+}
+
+// Run sends queued packets to the radio until the context is done.
+func (s *TxScheduler) Run(ctx context.Context) error {
+	for {
+		item, wait, timeout := s.next()
+		if item == nil {
+			timer := time.NewTimer(timeout)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-wait:
+				timer.Stop()
+			case <-timer.C:
+				s.expire()
+			}
+			continue
+		}
+
+		if err := s.transport.SendToRadio(ctx, item.frame); err != nil {
+			s.lock.Lock()
+			s.complete(item, err)
+			s.lock.Unlock()
+		}
+	}
+}
+
+// next returns the packet to send. If there is none, it returns a channel signalling state
+// changes and the time after which the state should be re-evaluated anyway.
+func (s *TxScheduler) next() (*txItem, <-chan struct{}, time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	timeout := s.StatusTimeout
+	if timeout <= 0 {
+		timeout = DefaultStatusTimeout
+	}
+	switch {
+	case s.inflight != nil:
+		return nil, s.changed, time.Until(s.inflight.deadline)
+	case s.queue.Len() == 0:
+		return nil, s.changed, time.Hour
+	case s.free == 0:
+		// the radio frees slots as it transmits, but doesn't report it without being asked
+		return nil, s.changed, timeout
+	}
+
+	item := heap.Pop(&s.queue).(*txItem)
+	item.deadline = time.Now().Add(timeout)
+	s.inflight = item
+	s.notify()
+	return item, nil, 0
+}
+
+// expire is called when no queue status arrived in time.
+func (s *TxScheduler) expire() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch {
+	case s.inflight != nil && time.Now().After(s.inflight.deadline):
+		s.complete(s.inflight, nil)
+	case s.inflight == nil && s.free == 0:
+		// try sending, the queue status of the packet tells whether the radio still is full
+		s.free = -1
+	}
+}
+
+func (s *TxScheduler) handleStatus(status *proto.QueueStatus) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.free = int(status.GetFree())
+	s.maxLen = int(status.GetMaxlen())
+	if s.inflight != nil {
+		id := s.inflight.frame.GetPacket().GetId()
+		switch {
+		case id != 0 && status.GetMeshPacketId() != 0 && status.GetMeshPacketId() != id:
+			// status of another packet, e.g. one sent by the firmware itself, ignored
+		case status.GetRes() != 0 && status.GetFree() == 0:
+			// the radio queue is full, the packet is sent again when there is room
+			item := s.inflight
+			s.inflight = nil
+			heap.Push(&s.queue, item)
+		case status.GetRes() != 0:
+			s.complete(s.inflight, QueueError{Res: status.GetRes(), Message: s.inflight.notice})
+		default:
+			s.complete(s.inflight, nil)
+		}
+	}
+	s.notify()
+}
+
+// handleNotification keeps the message of a notification about the packet being sent.
+// The firmware sends it before the queue status.
+func (s *TxScheduler) handleNotification(notification *proto.ClientNotification) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.inflight != nil && notification.GetReplyId() != 0 &&
+		notification.GetReplyId() == s.inflight.frame.GetPacket().GetId() {
+		s.inflight.notice = notification.GetMessage()
+	}
+}
+
+func (s *TxScheduler) push(ctx context.Context, item *txItem) error {
+	maxQueued := s.MaxQueued
+	if maxQueued <= 0 {
+		maxQueued = DefaultMaxQueued
+	}
+
+	for {
+		s.lock.Lock()
+		if s.queue.Len() < maxQueued {
+			s.seq++
+			item.seq = s.seq
+			heap.Push(&s.queue, item)
+			s.notify()
+			s.lock.Unlock()
+			return nil
+		}
+		changed := s.changed
+		s.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// complete reports the result of the packet. The lock must be held.
+func (s *TxScheduler) complete(item *txItem, err error) {
+	if s.inflight == item {
+		s.inflight = nil
+	}
+	select {
+	case item.done <- err:
+	default: // already completed
+	}
+	s.notify()
+}
+
+// notify wakes up everyone waiting for a state change. The lock must be held.
+func (s *TxScheduler) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// txPriority returns the priority of the packet, defaulting like the firmware does.
+func txPriority(packet *proto.MeshPacket) proto.MeshPacket_Priority {
+	switch {
+	case packet.GetPriority() != proto.MeshPacket_UNSET:
+		return packet.GetPriority()
+	case packet.GetWantAck():
+		return proto.MeshPacket_RELIABLE
+	default:
+		return proto.MeshPacket_DEFAULT
+	}
+}
+
+type txItem struct {
+	frame    *proto.ToRadio
+	priority proto.MeshPacket_Priority
+	seq      uint64
+	deadline time.Time
+	done     chan error
+	// notice is the message of a client notification about the packet
+	notice string
+	// index is the position in the queue, -1 after leaving it
+	index int
+}
+
+// txQueue is a heap of packets ordered by priority, then by the order of sending.
+type txQueue []*txItem
+
+func (q txQueue) Len() int { return len(q) }
+
+func (q txQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q txQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *txQueue) Push(x any) {
+	item := x.(*txItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *txQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	item.index = -1
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,225 @@
+package meshtastic
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// fakeRadio records IDs of sent packets and returns frames queued by the test.
+type fakeRadio struct {
+	sent   chan uint32
+	frames chan *proto.FromRadio
+}
+
+func newFakeRadio() *fakeRadio {
+	return &fakeRadio{sent: make(chan uint32, 16), frames: make(chan *proto.FromRadio, 16)}
+}
+
+func (r *fakeRadio) SendToRadio(ctx context.Context, frame *proto.ToRadio) error {
+	select {
+	case r.sent <- frame.GetPacket().GetId():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *fakeRadio) ReceiveFromRadio(ctx context.Context) (*proto.FromRadio, error) {
+	select {
+	case frame := <-r.frames:
+		return frame, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func startScheduler(t *testing.T, statusTimeout time.Duration) (*TxScheduler, *fakeRadio, context.Context) {
+	t.Helper()
+	radio := newFakeRadio()
+	s := NewTxScheduler(radio)
+	s.StatusTimeout = statusTimeout
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	go func() { _ = s.Run(ctx) }()
+	return s, radio, ctx
+}
+
+// reportStatus makes the scheduler receive the queue status from the radio.
+func reportStatus(t *testing.T, ctx context.Context, s *TxScheduler, radio *fakeRadio, status *proto.QueueStatus) {
+	t.Helper()
+	radio.frames <- &proto.FromRadio{PayloadVariant: &proto.FromRadio_QueueStatus{QueueStatus: status}}
+	if _, err := s.ReceiveFromRadio(ctx); err != nil {
+		t.Fatalf("failed to receive queue status: %v", err)
+	}
+}
+
+func sendAsync(ctx context.Context, s *TxScheduler, packet *proto.MeshPacket) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- s.SendToRadio(ctx, &proto.ToRadio{PayloadVariant: &proto.ToRadio_Packet{Packet: packet}})
+	}()
+	return result
+}
+
+func waitQueued(t *testing.T, s *TxScheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for s.Queued() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued %d packets, want %d", s.Queued(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func receiveSent(t *testing.T, radio *fakeRadio) uint32 {
+	t.Helper()
+	select {
+	case id := <-radio.sent:
+		return id
+	case <-time.After(time.Second):
+		t.Fatal("no packet sent to the radio")
+		return 0
+	}
+}
+
+func TestTxQueueOrder(t *testing.T) {
+	items := []*txItem{
+		{seq: 1, priority: proto.MeshPacket_BACKGROUND},
+		{seq: 2, priority: proto.MeshPacket_DEFAULT},
+		{seq: 3, priority: proto.MeshPacket_RELIABLE},
+		{seq: 4, priority: proto.MeshPacket_DEFAULT},
+		{seq: 5, priority: proto.MeshPacket_ACK},
+		{seq: 6, priority: proto.MeshPacket_RELIABLE},
+	}
+	var queue txQueue
+	for _, item := range items {
+		heap.Push(&queue, item)
+	}
+
+	var order []uint64
+	for queue.Len() > 0 {
+		item := heap.Pop(&queue).(*txItem)
+		if item.index != -1 {
+			t.Errorf("popped item %d has index %d, want -1", item.seq, item.index)
+		}
+		order = append(order, item.seq)
+	}
+	if want := []uint64{5, 3, 6, 2, 4, 1}; !slices.Equal(order, want) {
+		t.Errorf("pop order %v, want %v", order, want)
+	}
+}
+
+func TestTxSchedulerPriority(t *testing.T) {
+	s, radio, ctx := startScheduler(t, time.Hour)
+
+	// hold packets locally until the radio reports room
+	reportStatus(t, ctx, s, radio, &proto.QueueStatus{Free: 0, Maxlen: 16})
+	packets := []*proto.MeshPacket{
+		{Id: 1, Priority: proto.MeshPacket_BACKGROUND},
+		{Id: 2},
+		{Id: 3, WantAck: true},
+		{Id: 4},
+	}
+	var results []<-chan error
+	for i, packet := range packets {
+		results = append(results, sendAsync(ctx, s, packet))
+		waitQueued(t, s, i+1)
+	}
+
+	reportStatus(t, ctx, s, radio, &proto.QueueStatus{Free: 16, Maxlen: 16})
+	var order []uint32
+	for range packets {
+		id := receiveSent(t, radio)
+		order = append(order, id)
+		reportStatus(t, ctx, s, radio, &proto.QueueStatus{Free: 15, Maxlen: 16, MeshPacketId: id})
+	}
+	if want := []uint32{3, 2, 4, 1}; !slices.Equal(order, want) {
+		t.Errorf("sent order %v, want %v", order, want)
+	}
+	for _, result := range results {
+		if err := <-result; err != nil {
+			t.Errorf("unexpected send error: %v", err)
+		}
+	}
+}
+
+func TestTxSchedulerRequeueWhenFull(t *testing.T) {
+	s, radio, ctx := startScheduler(t, 50*time.Millisecond)
+
+	result := sendAsync(ctx, s, &proto.MeshPacket{Id: 1})
+	if id := receiveSent(t, radio); id != 1 {
+		t.Fatalf("sent packet %d, want 1", id)
+	}
+	reportStatus(t, ctx, s, radio, &proto.QueueStatus{Res: 1, Free: 0, Maxlen: 16, MeshPacketId: 1})
+	if queued := s.Queued(); queued != 1 {
+		t.Fatalf("queued %d packets after the radio refused one, want 1", queued)
+	}
+
+	// the scheduler retries after the status timeout
+	if id := receiveSent(t, radio); id != 1 {
+		t.Fatalf("resent packet %d, want 1", id)
+	}
+	reportStatus(t, ctx, s, radio, &proto.QueueStatus{Free: 15, Maxlen: 16, MeshPacketId: 1})
+	if err := <-result; err != nil {
+		t.Errorf("unexpected send error: %v", err)
+	}
+}
+
+func TestTxSchedulerQueueError(t *testing.T) {
+	s, radio, ctx := startScheduler(t, time.Hour)
+
+	result := sendAsync(ctx, s, &proto.MeshPacket{Id: 1})
+	receiveSent(t, radio)
+	radio.frames <- &proto.FromRadio{PayloadVariant: &proto.FromRadio_ClientNotification{
+		ClientNotification: &proto.ClientNotification{ReplyId: protobuf.Uint32(1), Message: "Duty cycle limit exceeded"},
+	}}
+	if frame, err := s.ReceiveFromRadio(ctx); err != nil || frame.GetClientNotification() == nil {
+		t.Fatalf("notification not passed to the caller: %v, %v", frame, err)
+	}
+	reportStatus(t, ctx, s, radio, &proto.QueueStatus{Res: 5, Free: 10, Maxlen: 16, MeshPacketId: 1})
+
+	var queueErr QueueError
+	if err := <-result; !errors.As(err, &queueErr) || queueErr.Res != 5 {
+		t.Fatalf("got error %v, want QueueError 5", err)
+	}
+	if queueErr.Message != "Duty cycle limit exceeded" {
+		t.Errorf("got message %q, want the notification message", queueErr.Message)
+	}
+}
+
+func TestTxSchedulerStatusTimeout(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	s, radio, ctx := startScheduler(t, timeout)
+
+	start := time.Now()
+	result := sendAsync(ctx, s, &proto.MeshPacket{Id: 1})
+	receiveSent(t, radio)
+
+	// firmware without queue status reports: the packet is assumed accepted
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("unexpected send error: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < timeout {
+			t.Errorf("send completed after %v, before the status timeout", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("send not completed after the status timeout")
+	}
+
+	// the next packet is not held back by the missing status
+	sendAsync(ctx, s, &proto.MeshPacket{Id: 2})
+	if id := receiveSent(t, radio); id != 2 {
+		t.Errorf("sent packet %d, want 2", id)
+	}
+}
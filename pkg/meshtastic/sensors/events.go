@@ -0,0 +1,83 @@
+// Package sensors decodes outputs of the paxcounter and detection sensor modules, keeps
+// per-node time series and calls back on threshold crossings and state changes.
+package sensors
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// bell is appended to detection messages when the sensor is configured to send a bell.
+const bell = "\a"
+
+// PaxSample is a paxcounter report of a node.
+type PaxSample struct {
+	Node uint32
+	Time time.Time
+	// WiFi is the number of seen Wi-Fi devices.
+	WiFi uint32
+	// BLE is the number of seen Bluetooth devices.
+	BLE uint32
+	// Uptime is the uptime of the reporting node.
+	Uptime time.Duration
+}
+
+// Total returns the number of seen Wi-Fi and Bluetooth devices.
+func (s PaxSample) Total() uint32 {
+	return s.WiFi + s.BLE
+}
+
+// DetectionEvent is a detection sensor report of a node.
+type DetectionEvent struct {
+	Node uint32
+	Time time.Time
+	// Name is the sensor name configured on the node.
+	Name string
+	// Detected is the state of the monitored pin: true for a detection, false for a periodic
+	// state report of an idle sensor.
+	Detected bool
+	// Bell reports whether the sensor asked to ring a bell on receiving nodes.
+	Bell bool
+	// Text is the original message text.
+	Text string
+}
+
+// ParsePaxcount decodes the payload of a PAXCOUNTER_APP packet.
+func ParsePaxcount(node uint32, at time.Time, payload []byte) (PaxSample, error) {
+	pax := new(proto.Paxcount)
+	if err := protobuf.Unmarshal(payload, pax); err != nil {
+		return PaxSample{}, err
+	}
+	return PaxSample{
+		Node:   node,
+		Time:   at,
+		WiFi:   pax.GetWifi(),
+		BLE:    pax.GetBle(),
+		Uptime: time.Duration(pax.GetUptime()) * time.Second,
+	}, nil
+}
+
+// ParseDetection decodes the payload of a DETECTION_SENSOR_APP packet. Firmware sends
+// "<name> detected" on detection and "<name> state: <0|1>" in periodic state reports.
+func ParseDetection(node uint32, at time.Time, payload []byte) DetectionEvent {
+	text := string(payload)
+	event := DetectionEvent{Node: node, Time: at, Text: text, Detected: true}
+
+	text, event.Bell = strings.CutSuffix(text, bell)
+	text = strings.TrimSpace(text)
+	if name, ok := strings.CutSuffix(text, " detected"); ok {
+		event.Name = name
+	} else if name, state, ok := strings.Cut(text, " state: "); ok {
+		event.Name = name
+		if value, err := strconv.Atoi(strings.TrimSpace(state)); err == nil {
+			event.Detected = value != 0
+		}
+	} else {
+		event.Name = text
+	}
+	return event
+}
@@ -0,0 +1,211 @@
+package sensors
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// DefaultMaxSamples is the number of samples kept per node if Monitor.MaxSamples is not set.
+const DefaultMaxSamples = 1024
+
+// PaxThreshold calls back when the total device count of a node crosses a value.
+type PaxThreshold struct {
+	// Value is the device count at which the threshold is crossed upwards.
+	Value uint32
+	// Hysteresis is subtracted from Value for downward crossings, to avoid flapping
+	// around the threshold.
+	Hysteresis uint32
+	// OnRise is called when the count reaches Value. May be nil.
+	OnRise func(PaxSample)
+	// OnFall is called when the count drops below Value minus Hysteresis. May be nil.
+	OnFall func(PaxSample)
+}
+
+// Monitor collects paxcounter and detection sensor reports of mesh nodes.
+// It is safe for concurrent use. Callbacks are called synchronously from Handle.
+type Monitor struct {
+	// MaxSamples limits the number of samples of each kind kept per node. Defaults to DefaultMaxSamples.
+	MaxSamples int
+	// MaxAge is the time after which samples are dropped. Zero means samples are kept
+	// until MaxSamples is reached.
+	MaxAge time.Duration
+	// OnPaxcount is called for every paxcounter report. May be nil.
+	OnPaxcount func(PaxSample)
+	// OnDetection is called for every detection sensor report. May be nil.
+	OnDetection func(DetectionEvent)
+	// OnDetectionChange is called when the detection state of a sensor differs from its
+	// previous report, including the first report of a sensor. May be nil.
+	OnDetectionChange func(DetectionEvent)
+
+	lock       sync.RWMutex
+	pax        map[uint32][]PaxSample
+	detections map[uint32][]DetectionEvent
+	thresholds []*paxThresholdState
+}
+
+type paxThresholdState struct {
+	PaxThreshold
+	above map[uint32]bool
+}
+
+// AddPaxThreshold registers a threshold checked against paxcounter reports of every node.
+func (m *Monitor) AddPaxThreshold(threshold PaxThreshold) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.thresholds = append(m.thresholds, &paxThresholdState{
+		PaxThreshold: threshold,
+		above:        make(map[uint32]bool),
+	})
+}
+
+// Run collects reports from the receiver until the context is cancelled or the receiver fails.
+func (m *Monitor) Run(ctx context.Context, receiver meshtastic.PacketReceiver) error {
+	for {
+		packet, err := receiver.ReceiveFromMesh(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m.Handle(packet)
+	}
+}
+
+// Handle processes the packet if it carries a paxcounter or detection sensor report.
+// It returns false otherwise.
+func (m *Monitor) Handle(packet *proto.MeshPacket) bool {
+	at := time.Now()
+	if packet.GetRxTime() != 0 {
+		at = time.Unix(int64(packet.GetRxTime()), 0)
+	}
+
+	decoded := packet.GetDecoded()
+	switch decoded.GetPortnum() {
+	case proto.PortNum_PAXCOUNTER_APP:
+		sample, err := ParsePaxcount(packet.GetFrom(), at, decoded.GetPayload())
+		if err != nil {
+			Logger.Debug("Invalid paxcount", "from", meshtastic.FormatNodeID(packet.GetFrom()))
+			return false
+		}
+		m.handlePaxcount(sample)
+		return true
+	case proto.PortNum_DETECTION_SENSOR_APP:
+		m.handleDetection(ParseDetection(packet.GetFrom(), at, decoded.GetPayload()))
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *Monitor) handlePaxcount(sample PaxSample) {
+	var callbacks []func(PaxSample)
+
+	m.lock.Lock()
+	if m.pax == nil {
+		m.pax = make(map[uint32][]PaxSample)
+	}
+	m.pax[sample.Node] = trimSeries(m, append(m.pax[sample.Node], sample), func(s PaxSample) time.Time { return s.Time })
+
+	total := sample.Total()
+	for _, threshold := range m.thresholds {
+		above := threshold.above[sample.Node]
+		switch {
+		case !above && total >= threshold.Value:
+			threshold.above[sample.Node] = true
+			callbacks = append(callbacks, threshold.OnRise)
+		case above && total+threshold.Hysteresis < threshold.Value:
+			threshold.above[sample.Node] = false
+			callbacks = append(callbacks, threshold.OnFall)
+		}
+	}
+	m.lock.Unlock()
+
+	if m.OnPaxcount != nil {
+		m.OnPaxcount(sample)
+	}
+	for _, callback := range callbacks {
+		if callback != nil {
+			callback(sample)
+		}
+	}
+}
+
+func (m *Monitor) handleDetection(event DetectionEvent) {
+	m.lock.Lock()
+	if m.detections == nil {
+		m.detections = make(map[uint32][]DetectionEvent)
+	}
+	history := m.detections[event.Node]
+	changed := true
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Name == event.Name {
+			changed = history[i].Detected != event.Detected
+			break
+		}
+	}
+	m.detections[event.Node] = trimSeries(m, append(history, event), func(e DetectionEvent) time.Time { return e.Time })
+	m.lock.Unlock()
+
+	if m.OnDetection != nil {
+		m.OnDetection(event)
+	}
+	if changed && m.OnDetectionChange != nil {
+		m.OnDetectionChange(event)
+	}
+}
+
+// Paxcounts returns the paxcounter reports of a node, oldest first.
+func (m *Monitor) Paxcounts(node uint32) []PaxSample {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return slices.Clone(m.pax[node])
+}
+
+// Detections returns the detection sensor reports of a node, oldest first.
+func (m *Monitor) Detections(node uint32) []DetectionEvent {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return slices.Clone(m.detections[node])
+}
+
+// Nodes returns the node numbers of all nodes with reports, in ascending order.
+func (m *Monitor) Nodes() []uint32 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	nodes := slices.Collect(maps.Keys(m.pax))
+	for node := range m.detections {
+		if _, ok := m.pax[node]; !ok {
+			nodes = append(nodes, node)
+		}
+	}
+	slices.Sort(nodes)
+	return nodes
+}
+
+// trimSeries drops samples exceeding the count and age limits. Samples must be ordered by time.
+func trimSeries[T any](m *Monitor, series []T, timeOf func(T) time.Time) []T {
+	maxSamples := m.MaxSamples
+	if maxSamples <= 0 {
+		maxSamples = DefaultMaxSamples
+	}
+	if len(series) > maxSamples {
+		series = slices.Delete(series, 0, len(series)-maxSamples)
+	}
+	if m.MaxAge > 0 && len(series) > 0 {
+		deadline := timeOf(series[len(series)-1]).Add(-m.MaxAge)
+		i := 0
+		for i < len(series) && timeOf(series[i]).Before(deadline) {
+			i++
+		}
+		series = slices.Delete(series, 0, i)
+	}
+	return series
+}
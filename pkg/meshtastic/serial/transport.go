@@ -34,14 +34,16 @@ var _ meshtastic.HardwareTransport = &StreamTransport{}
 // StreamTransport represents a transport layer using a Stream (e.g., TCP connection or serial port).
 type StreamTransport struct {
 	Stream io.ReadWriteCloser
-	lock   sync.Mutex
+	// readLock and writeLock are separate, so a pending read does not block sending.
+	readLock  sync.Mutex
+	writeLock sync.Mutex
 }
 
 // ReceiveFromRadio reads a single packet from the stream and returns it.
 func (st *StreamTransport) ReceiveFromRadio(ctx context.Context) (*proto.FromRadio, error) {
-	st.lock.Lock()
+	st.readLock.Lock()
 	buf, err := st.readBytes()
-	st.lock.Unlock()
+	st.readLock.Unlock()
 	if err != nil {
 		return nil, err
 	}
@@ -97,8 +99,8 @@ func (st *StreamTransport) SendToRadio(ctx context.Context, packet *proto.ToRadi
 		return fmt.Errorf("marshalling error: %w", err)
 	}
 
-	st.lock.Lock()
-	defer st.lock.Unlock()
+	st.writeLock.Lock()
+	defer st.writeLock.Unlock()
 	return st.sendBytes(buf)
 }
 
@@ -0,0 +1,363 @@
+// Package serialtunnel exposes the UART of a remote node running the serial module
+// as a net.Conn. Bytes are exchanged with the node on SERIAL_APP.
+package serialtunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// MaxChunkSize is the largest payload of a single mesh packet.
+const MaxChunkSize = int(proto.Constants_DATA_PAYLOAD_LEN)
+
+const (
+	defaultAckTimeout = 30 * time.Second
+	defaultReadQueue  = 64
+	// recentPackets is the number of received packet IDs remembered to drop duplicates.
+	recentPackets = 64
+)
+
+// Config holds options of a tunnel connection.
+type Config struct {
+	// LocalNode is the node number reported as the local address.
+	LocalNode uint32
+	// ChannelIndex is the channel used for serial packets.
+	ChannelIndex uint32
+	// WantAck makes each write chunk wait for the delivery acknowledgement before the next
+	// chunk is sent. It guarantees that the remote UART receives chunks in order.
+	WantAck bool
+	// AckTimeout is the time to wait for an acknowledgement. Defaults to 30 seconds.
+	AckTimeout time.Duration
+	// ChunkSize is the maximum number of bytes in a packet. Defaults to MaxChunkSize.
+	ChunkSize int
+	// ReadQueue is the number of received packets buffered before the oldest are dropped.
+	// Defaults to 64.
+	ReadQueue int
+}
+
+// Addr is the address of a mesh node.
+type Addr uint32
+
+// Network returns the name of the network.
+func (a Addr) Network() string {
+	return "meshtastic"
+}
+
+func (a Addr) String() string {
+	return meshtastic.FormatNodeID(uint32(a))
+}
+
+// Conn is a byte stream to the serial port of a remote node.
+//
+// The connection reads all packets from the transport until it is closed, so it should own
+// the transport or receive serial and routing packets through a meshtastic.FrameRouter.
+type Conn struct {
+	transport meshtastic.MeshTransport
+	remote    uint32
+	config    Config
+	cancel    context.CancelFunc
+
+	incoming chan []byte
+	unread   []byte
+	readLock sync.Mutex
+
+	writeLock sync.Mutex
+	ackLock   sync.Mutex
+	acks      map[uint32]chan error
+
+	readDeadline  *deadline
+	writeDeadline *deadline
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+var _ net.Conn = &Conn{}
+
+// Dial creates a connection to the serial port of the remote node and starts receiving
+// packets from the transport.
+func Dial(transport meshtastic.MeshTransport, remote uint32, config Config) *Conn {
+	if config.AckTimeout <= 0 {
+		config.AckTimeout = defaultAckTimeout
+	}
+	if config.ChunkSize <= 0 || config.ChunkSize > MaxChunkSize {
+		config.ChunkSize = MaxChunkSize
+	}
+	if config.ReadQueue <= 0 {
+		config.ReadQueue = defaultReadQueue
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Conn{
+		transport:     transport,
+		remote:        remote,
+		config:        config,
+		cancel:        cancel,
+		incoming:      make(chan []byte, config.ReadQueue),
+		acks:          make(map[uint32]chan error),
+		readDeadline:  newDeadline(),
+		writeDeadline: newDeadline(),
+		closed:        make(chan struct{}),
+	}
+	go c.receive(ctx)
+	return c
+}
+
+// Read reads bytes received from the remote serial port.
+func (c *Conn) Read(b []byte) (int, error) {
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+
+	for len(c.unread) == 0 {
+		select {
+		case chunk := <-c.incoming:
+			c.unread = chunk
+		case <-c.closed:
+			// deliver what is already received before reporting the close
+			select {
+			case chunk := <-c.incoming:
+				c.unread = chunk
+			default:
+				return 0, c.err
+			}
+		case <-c.readDeadline.wait():
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+
+	n := copy(b, c.unread)
+	c.unread = c.unread[n:]
+	return n, nil
+}
+
+// Write sends bytes to the remote serial port, split into packets of at most ChunkSize bytes.
+func (c *Conn) Write(b []byte) (int, error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.writeDeadline.wait():
+			cancel()
+		case <-c.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	written := 0
+	for written < len(b) {
+		end := min(written+c.config.ChunkSize, len(b))
+		if err := c.sendChunk(ctx, b[written:end]); err != nil {
+			return written, c.writeError(err)
+		}
+		written = end
+	}
+	return written, nil
+}
+
+// Close stops receiving packets. Blocked reads and writes are unblocked.
+func (c *Conn) Close() error {
+	c.shutdown(net.ErrClosed)
+	return nil
+}
+
+// LocalAddr returns the local node address.
+func (c *Conn) LocalAddr() net.Addr {
+	return Addr(c.config.LocalNode)
+}
+
+// RemoteAddr returns the remote node address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return Addr(c.remote)
+}
+
+// SetDeadline sets the read and write deadlines.
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	c.writeDeadline.set(t)
+	return nil
+}
+
+// SetReadDeadline sets the deadline for Read calls.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for Write calls.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.set(t)
+	return nil
+}
+
+func (c *Conn) sendChunk(ctx context.Context, chunk []byte) error {
+	packet := &proto.MeshPacket{
+		// the ID is assigned here to register the acknowledgement before it can arrive,
+		// it is never zero, which the device would replace
+		Id:      rand.Uint32() | 1,
+		To:      c.remote,
+		Channel: c.config.ChannelIndex,
+		PayloadVariant: &proto.MeshPacket_Decoded{
+			Decoded: &proto.Data{
+				Portnum: proto.PortNum_SERIAL_APP,
+				Payload: chunk,
+			},
+		},
+		WantAck: c.config.WantAck,
+	}
+
+	var ack chan error
+	if c.config.WantAck {
+		ack = make(chan error, 1)
+		c.ackLock.Lock()
+		c.acks[packet.Id] = ack
+		c.ackLock.Unlock()
+		defer func() {
+			c.ackLock.Lock()
+			delete(c.acks, packet.Id)
+			c.ackLock.Unlock()
+		}()
+	}
+
+	if err := c.transport.SendToMesh(ctx, packet); err != nil {
+		return fmt.Errorf("failed to send serial packet: %w", err)
+	}
+	if ack == nil {
+		return nil
+	}
+
+	timer := time.NewTimer(c.config.AckTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-ack:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("serial packet %#x is not acknowledged", packet.Id)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Conn) writeError(err error) error {
+	select {
+	case <-c.closed:
+		return c.err
+	default:
+	}
+	select {
+	case <-c.writeDeadline.wait():
+		return os.ErrDeadlineExceeded
+	default:
+		return err
+	}
+}
+
+func (c *Conn) receive(ctx context.Context) {
+	var recent [recentPackets]uint32
+	next := 0
+
+	for {
+		packet, err := c.transport.ReceiveFromMesh(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				c.shutdown(err)
+			}
+			return
+		}
+		decoded := packet.GetDecoded()
+		switch decoded.GetPortnum() {
+		case proto.PortNum_SERIAL_APP:
+			if packet.GetFrom() != c.remote {
+				continue
+			}
+			if packet.GetId() != 0 && containsID(recent[:], packet.GetId()) {
+				continue // retransmission
+			}
+			recent[next] = packet.GetId()
+			next = (next + 1) % recentPackets
+			c.enqueue(decoded.GetPayload())
+		case proto.PortNum_ROUTING_APP:
+			// routing errors may come from the local node or a relay, acks are
+			// matched by the request ID only
+			c.handleRouting(decoded)
+		}
+	}
+}
+
+// enqueue queues received bytes, dropping the oldest chunk if the reader is not keeping up.
+func (c *Conn) enqueue(chunk []byte) {
+	if len(chunk) == 0 {
+		return
+	}
+	for {
+		select {
+		case c.incoming <- chunk:
+			return
+		default:
+		}
+		select {
+		case <-c.incoming:
+			Logger.Warn("Reader is not keeping up. Serial data dropped")
+		default:
+		}
+	}
+}
+
+func (c *Conn) handleRouting(decoded *proto.Data) {
+	c.ackLock.Lock()
+	ack, ok := c.acks[decoded.GetRequestId()]
+	c.ackLock.Unlock()
+	if !ok {
+		return
+	}
+
+	routing := new(proto.Routing)
+	if err := protobuf.Unmarshal(decoded.GetPayload(), routing); err != nil {
+		return
+	}
+	var err error
+	if reason := routing.GetErrorReason(); reason != proto.Routing_NONE {
+		err = meshtastic.RoutingError{Reason: reason}
+	}
+	select {
+	case ack <- err:
+	default:
+	}
+}
+
+func (c *Conn) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		if !errors.Is(err, net.ErrClosed) {
+			err = fmt.Errorf("failed to receive serial packets: %w", err)
+		}
+		c.err = err
+		c.cancel()
+		close(c.closed)
+	})
+}
+
+func containsID(ids []uint32, id uint32) bool {
+	for _, known := range ids {
+		if known == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+// Package sim provides an in-memory mesh for running and testing applications without radios.
+package sim
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultQueueSize = 256
+	defaultHopLimit  = 3
+)
+
+// ErrPayloadTooLarge is returned when a packet payload does not fit into a mesh packet.
+var ErrPayloadTooLarge = errors.New("payload is too large")
+
+// Mesh connects simulated nodes. Every node hears every other node directly.
+// It is safe for concurrent use.
+type Mesh struct {
+	// Loss is the probability of a packet being lost on the way to a receiver.
+	Loss float64
+	// Latency delays every delivery.
+	Latency time.Duration
+	// QueueSize is the number of packets a node buffers before new packets are dropped.
+	// Defaults to 256.
+	QueueSize int
+
+	lock  sync.RWMutex
+	nodes map[uint32]*Node
+}
+
+// Node returns the simulated node with the given number, creating it if needed.
+func (m *Mesh) Node(num uint32) *Node {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.nodes == nil {
+		m.nodes = make(map[uint32]*Node)
+	}
+	if node, ok := m.nodes[num]; ok {
+		return node
+	}
+
+	queueSize := m.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	node := &Node{mesh: m, num: num, inbox: make(chan *proto.MeshPacket, queueSize)}
+	m.nodes[num] = node
+	return node
+}
+
+// deliver hands a copy of the packet to every receiver and returns the nodes which got it.
+func (m *Mesh) deliver(packet *proto.MeshPacket) []*Node {
+	m.lock.RLock()
+	var receivers []*Node
+	for num, node := range m.nodes {
+		if num == packet.GetFrom() {
+			continue
+		}
+		if packet.GetTo() == meshtastic.BroadcastNodenum || packet.GetTo() == num {
+			receivers = append(receivers, node)
+		}
+	}
+	m.lock.RUnlock()
+
+	delivered := receivers[:0]
+	for _, node := range receivers {
+		if m.Loss > 0 && rand.Float64() < m.Loss {
+			continue
+		}
+		delivered = append(delivered, node)
+		copied := protobuf.Clone(packet).(*proto.MeshPacket)
+		if m.Latency > 0 {
+			time.AfterFunc(m.Latency, func() { node.receive(copied) })
+		} else {
+			node.receive(copied)
+		}
+	}
+	return delivered
+}
+
+// Node is a simulated node. It implements meshtastic.MeshTransport.
+type Node struct {
+	mesh  *Mesh
+	num   uint32
+	inbox chan *proto.MeshPacket
+
+	lock   sync.Mutex
+	nextID uint32
+}
+
+var _ meshtastic.MeshTransport = &Node{}
+
+// Num returns the node number.
+func (n *Node) Num() uint32 {
+	return n.num
+}
+
+// SendToMesh sends the packet to its destination. Unicast packets which want an acknowledgement
+// are acknowledged by the receiver on ROUTING_APP.
+func (n *Node) SendToMesh(_ context.Context, packet *proto.MeshPacket) error {
+	if len(packet.GetDecoded().GetPayload()) > int(proto.Constants_DATA_PAYLOAD_LEN) {
+		return ErrPayloadTooLarge
+	}
+
+	packet.From = n.num
+	if packet.Id == 0 {
+		packet.Id = n.generatePacketID()
+	}
+	if packet.HopLimit == 0 {
+		packet.HopLimit = defaultHopLimit
+	}
+	packet.HopStart = packet.HopLimit
+	packet.RxTime = uint32(time.Now().Unix())
+
+	delivered := n.mesh.deliver(packet)
+	if packet.GetWantAck() && packet.GetTo() != meshtastic.BroadcastNodenum && len(delivered) > 0 {
+		delivered[0].acknowledge(packet)
+	}
+	return nil
+}
+
+// ReceiveFromMesh returns the next packet heard by the node.
+func (n *Node) ReceiveFromMesh(ctx context.Context) (*proto.MeshPacket, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case packet := <-n.inbox:
+		return packet, nil
+	}
+}
+
+func (n *Node) receive(packet *proto.MeshPacket) {
+	select {
+	case n.inbox <- packet:
+	default:
+		Logger.Warn("Node queue is full. Packet dropped", "node", meshtastic.FormatNodeID(n.num))
+	}
+}
+
+func (n *Node) acknowledge(packet *proto.MeshPacket) {
+	payload, err := protobuf.Marshal(&proto.Routing{
+		Variant: &proto.Routing_ErrorReason{ErrorReason: proto.Routing_NONE},
+	})
+	if err != nil {
+		return
+	}
+	_ = n.SendToMesh(context.Background(), &proto.MeshPacket{
+		To: packet.GetFrom(),
+		PayloadVariant: &proto.MeshPacket_Decoded{
+			Decoded: &proto.Data{
+				Portnum:   proto.PortNum_ROUTING_APP,
+				Payload:   payload,
+				RequestId: packet.GetId(),
+			},
+		},
+	})
+}
+
+func (n *Node) generatePacketID() uint32 {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.nextID++
+	return n.nextID&0x3ff | rand.Uint32()<<10
+}
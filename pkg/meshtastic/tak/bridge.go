@@ -0,0 +1,162 @@
+package tak
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// DefaultMulticastAddress is the UDP multicast group ATAK uses for situational awareness.
+const DefaultMulticastAddress = "239.2.3.1:6969"
+
+// Bridge forwards TAK packets from the mesh to a CoT endpoint and CoT events from the endpoint
+// to the mesh. The endpoint is usually a TCP connection to a TAK server or client, or a
+// MulticastEndpoint joined to DefaultMulticastAddress.
+type Bridge struct {
+	// Mesh is used to exchange packets with other nodes.
+	Mesh meshtastic.MeshTransport
+	// Converter converts packets and events.
+	Converter Converter
+	// Output receives CoT events converted from the mesh. May be nil.
+	Output io.Writer
+	// Input provides CoT events sent to the mesh. Each event must be a complete XML document.
+	// May be nil.
+	Input io.Reader
+	// ChannelIndex is the channel used for TAK packets.
+	ChannelIndex uint32
+
+	writeLock sync.Mutex
+}
+
+// Run forwards events until the context is cancelled or the mesh or endpoint fails.
+// A blocked read from Input is only interrupted by closing it.
+func (b *Bridge) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, 2)
+	go func() { errs <- b.downlink(ctx) }()
+	if b.Input != nil {
+		go func() { errs <- b.uplink(ctx) }()
+	}
+	return <-errs
+}
+
+// downlink writes mesh packets to the CoT endpoint.
+func (b *Bridge) downlink(ctx context.Context) error {
+	for {
+		packet, err := b.Mesh.ReceiveFromMesh(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		decoded := packet.GetDecoded()
+		var document []byte
+		switch decoded.GetPortnum() {
+		case proto.PortNum_ATAK_PLUGIN:
+			takPacket, err := b.Converter.Unmarshal(decoded.GetPayload())
+			if err != nil {
+				Logger.Debug("Invalid TAK packet", "from", meshtastic.FormatNodeID(packet.GetFrom()), "error", err)
+				continue
+			}
+			event, err := b.Converter.ToCoT(takPacket)
+			if err != nil {
+				Logger.Warn("TAK packet is not converted", "from", meshtastic.FormatNodeID(packet.GetFrom()), "error", err)
+				continue
+			}
+			if document, err = xml.Marshal(event); err != nil {
+				return fmt.Errorf("marshalling error: %w", err)
+			}
+		case proto.PortNum_ATAK_FORWARDER:
+			// only plain CoT documents are forwarded, the forwarder plugin's own encoding is not supported
+			document = bytes.TrimSpace(decoded.GetPayload())
+			if !bytes.HasPrefix(document, []byte("<")) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if err = b.WriteEvent(document); err != nil {
+			return err
+		}
+	}
+}
+
+// WriteEvent writes a CoT document to the output.
+func (b *Bridge) WriteEvent(document []byte) error {
+	if b.Output == nil {
+		return nil
+	}
+	b.writeLock.Lock()
+	defer b.writeLock.Unlock()
+	if _, err := b.Output.Write(append([]byte(xml.Header), document...)); err != nil {
+		return fmt.Errorf("failed to write CoT event: %w", err)
+	}
+	return nil
+}
+
+// uplink sends CoT events read from the endpoint to the mesh.
+func (b *Bridge) uplink(ctx context.Context) error {
+	decoder := xml.NewDecoder(b.Input)
+	for {
+		event := new(Event)
+		err := decoder.Decode(event)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// a broken document can't be skipped reliably, start over with the next read
+				Logger.Warn("Invalid CoT event", "error", err)
+				decoder = xml.NewDecoder(b.Input)
+				continue
+			}
+			return fmt.Errorf("failed to read CoT event: %w", err)
+		}
+		if err = b.SendEvent(ctx, event); err != nil {
+			Logger.Warn("CoT event is not sent", "uid", event.UID, "error", err)
+		}
+	}
+}
+
+// SendEvent converts a CoT event and broadcasts it to the mesh.
+func (b *Bridge) SendEvent(ctx context.Context, event *Event) error {
+	packet, err := b.Converter.FromCoT(event)
+	if err != nil {
+		return err
+	}
+	payload, err := b.Converter.Marshal(packet)
+	if err != nil {
+		return err
+	}
+	if len(payload) > int(proto.Constants_DATA_PAYLOAD_LEN) {
+		return fmt.Errorf("TAK packet of %d bytes does not fit into a mesh packet", len(payload))
+	}
+
+	err = b.Mesh.SendToMesh(ctx, &proto.MeshPacket{
+		To:      meshtastic.BroadcastNodenum,
+		Channel: b.ChannelIndex,
+		PayloadVariant: &proto.MeshPacket_Decoded{
+			Decoded: &proto.Data{
+				Portnum: proto.PortNum_ATAK_PLUGIN,
+				Payload: payload,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send TAK packet: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package tak
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+const (
+	// DefaultStale is the time after which converted events become stale.
+	DefaultStale = 10 * time.Minute
+	// AllChatRooms is the chat room of messages sent to everyone.
+	AllChatRooms = "All Chat Rooms"
+
+	coordinateScale = 1e-7
+)
+
+// StringCodec compresses the string fields of TAK packets. The firmware and the ATAK plugin
+// use Unishox2.
+type StringCodec interface {
+	Compress(s string) (string, error)
+	Decompress(s string) (string, error)
+}
+
+var teamNames = map[proto.Team]string{
+	proto.Team_White:      "White",
+	proto.Team_Yellow:     "Yellow",
+	proto.Team_Orange:     "Orange",
+	proto.Team_Magenta:    "Magenta",
+	proto.Team_Red:        "Red",
+	proto.Team_Maroon:     "Maroon",
+	proto.Team_Purple:     "Purple",
+	proto.Team_Dark_Blue:  "Dark Blue",
+	proto.Team_Blue:       "Blue",
+	proto.Team_Cyan:       "Cyan",
+	proto.Team_Teal:       "Teal",
+	proto.Team_Green:      "Green",
+	proto.Team_Dark_Green: "Dark Green",
+	proto.Team_Brown:      "Brown",
+}
+
+var roleNames = map[proto.MemberRole]string{
+	proto.MemberRole_TeamMember:      "Team Member",
+	proto.MemberRole_TeamLead:        "Team Lead",
+	proto.MemberRole_HQ:              "HQ",
+	proto.MemberRole_Sniper:          "Sniper",
+	proto.MemberRole_Medic:           "Medic",
+	proto.MemberRole_ForwardObserver: "Forward Observer",
+	proto.MemberRole_RTO:             "RTO",
+	proto.MemberRole_K9:              "K9",
+}
+
+// Converter converts TAK packets to CoT events and back.
+type Converter struct {
+	// Codec handles compressed string fields. Defaults to Unishox2.
+	Codec StringCodec
+	// Stale is the lifetime of converted events. Defaults to DefaultStale.
+	Stale time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// ToCoT converts a TAK packet to a CoT event.
+func (c *Converter) ToCoT(packet *proto.TAKPacket) (*Event, error) {
+	callsign, deviceCallsign := packet.GetContact().GetCallsign(), packet.GetContact().GetDeviceCallsign()
+	if packet.GetIsCompressed() {
+		var err error
+		if callsign, err = c.decompress(callsign); err != nil {
+			return nil, err
+		}
+		if deviceCallsign, err = c.decompress(deviceCallsign); err != nil {
+			return nil, err
+		}
+	}
+
+	now := c.now()
+	event := &Event{
+		Version: "2.0",
+		UID:     deviceCallsign,
+		Type:    TypeFriendlyGround,
+		Time:    FormatTime(now),
+		Start:   FormatTime(now),
+		Stale:   FormatTime(now.Add(c.stale())),
+		How:     HowGPS,
+		Point:   Point{HAE: unknownPoint, CE: unknownPoint, LE: unknownPoint},
+	}
+
+	switch variant := packet.GetPayloadVariant().(type) {
+	case *proto.TAKPacket_Pli:
+		pli := variant.Pli
+		event.Point.Lat = float64(pli.GetLatitudeI()) * coordinateScale
+		event.Point.Lon = float64(pli.GetLongitudeI()) * coordinateScale
+		event.Point.HAE = float64(pli.GetAltitude())
+		event.Detail = c.contactDetail(packet, callsign, deviceCallsign)
+		event.Detail.Track = &TrackDetail{Speed: float64(pli.GetSpeed()), Course: float64(pli.GetCourse())}
+	case *proto.TAKPacket_Chat:
+		if err := c.chatToCoT(event, packet, variant.Chat, callsign, deviceCallsign); err != nil {
+			return nil, err
+		}
+	case *proto.TAKPacket_Detail:
+		if err := xml.Unmarshal(wrapDetail(variant.Detail), &event.Detail); err != nil {
+			return nil, fmt.Errorf("invalid CoT detail: %w", err)
+		}
+		if event.Detail.Contact == nil && callsign != "" {
+			event.Detail.Contact = &ContactDetail{Callsign: callsign}
+		}
+	default:
+		return nil, errors.New("TAK packet has no payload")
+	}
+	return event, nil
+}
+
+func (c *Converter) contactDetail(packet *proto.TAKPacket, callsign, deviceCallsign string) Detail {
+	detail := Detail{
+		Contact: &ContactDetail{Callsign: callsign, Endpoint: "0.0.0.0:4242:tcp"},
+		UID:     &UIDDetail{Droid: callsign},
+	}
+	if group := packet.GetGroup(); group != nil {
+		detail.Group = &GroupDetail{Name: teamNames[group.GetTeam()], Role: roleNames[group.GetRole()]}
+	}
+	if status := packet.GetStatus(); status != nil {
+		detail.Status = &StatusDetail{Battery: status.GetBattery()}
+	}
+	return detail
+}
+
+func (c *Converter) chatToCoT(event *Event, packet *proto.TAKPacket, chat *proto.GeoChat, callsign, deviceCallsign string) error {
+	message, to := chat.GetMessage(), chat.GetTo()
+	if packet.GetIsCompressed() {
+		var err error
+		if message, err = c.decompress(message); err != nil {
+			return err
+		}
+		if to, err = c.decompress(to); err != nil {
+			return err
+		}
+	}
+	room := AllChatRooms
+	if to != "" && to != AllChatRooms {
+		room = to
+	}
+
+	messageID := newMessageID()
+	event.UID = fmt.Sprintf("GeoChat.%s.%s.%s", deviceCallsign, room, messageID)
+	event.Type = TypeGeoChat
+	event.How = HowHuman
+	event.Detail = Detail{
+		Chat: &ChatDetail{
+			Parent:         "RootContactGroup",
+			GroupOwner:     "false",
+			MessageID:      messageID,
+			Chatroom:       room,
+			ID:             room,
+			SenderCallsign: callsign,
+			Group:          &ChatGroup{UID0: deviceCallsign, UID1: room, ID: room},
+		},
+		Link: &LinkDetail{UID: deviceCallsign, Type: TypeFriendlyGround, Relation: "p-p"},
+		Remarks: &Remarks{
+			Source: "BAO.F.ATAK." + deviceCallsign,
+			To:     room,
+			Time:   event.Time,
+			Text:   message,
+		},
+	}
+	return nil
+}
+
+// FromCoT converts a CoT event to a TAK packet. Position reports and GeoChat messages
+// are converted to PLI and chat payloads, other events are sent as raw details.
+func (c *Converter) FromCoT(event *Event) (*proto.TAKPacket, error) {
+	packet := &proto.TAKPacket{
+		Contact: &proto.Contact{DeviceCallsign: event.UID},
+	}
+	if contact := event.Detail.Contact; contact != nil {
+		packet.Contact.Callsign = contact.Callsign
+	}
+	if group := event.Detail.Group; group != nil {
+		packet.Group = &proto.Group{Team: lookup(teamNames, group.Name), Role: lookup(roleNames, group.Role)}
+	}
+	if status := event.Detail.Status; status != nil {
+		packet.Status = &proto.Status{Battery: status.Battery}
+	}
+
+	switch {
+	case event.Type == TypeGeoChat && event.Detail.Chat != nil:
+		chat := event.Detail.Chat
+		packet.Contact.Callsign = chat.SenderCallsign
+		if link := event.Detail.Link; link != nil {
+			packet.Contact.DeviceCallsign = link.UID
+		} else if group := chat.Group; group != nil {
+			packet.Contact.DeviceCallsign = group.UID0
+		}
+		geoChat := new(proto.GeoChat)
+		if event.Detail.Remarks != nil {
+			geoChat.Message = event.Detail.Remarks.Text
+		}
+		if chat.ID != "" && chat.ID != AllChatRooms {
+			to := chat.ID
+			geoChat.To = &to
+		}
+		packet.PayloadVariant = &proto.TAKPacket_Chat{Chat: geoChat}
+	case strings.HasPrefix(event.Type, "a-"):
+		pli := &proto.PLI{
+			LatitudeI:  int32(event.Point.Lat / coordinateScale),
+			LongitudeI: int32(event.Point.Lon / coordinateScale),
+		}
+		if event.Point.HAE != unknownPoint {
+			pli.Altitude = int32(event.Point.HAE)
+		}
+		if track := event.Detail.Track; track != nil {
+			pli.Speed, pli.Course = uint32(max(track.Speed, 0)), uint32(max(track.Course, 0))
+		}
+		packet.PayloadVariant = &proto.TAKPacket_Pli{Pli: pli}
+	default:
+		raw, err := xml.Marshal(event.Detail)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling error: %w", err)
+		}
+		packet.PayloadVariant = &proto.TAKPacket_Detail{Detail: unwrapDetail(raw)}
+	}
+	return packet, nil
+}
+
+func (c *Converter) decompress(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	text, err := c.codec().Decompress(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress TAK packet: %w", err)
+	}
+	return text, nil
+}
+
+func (c *Converter) codec() StringCodec {
+	if c.Codec == nil {
+		return Unishox2{}
+	}
+	return c.Codec
+}
+
+func (c *Converter) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+func (c *Converter) stale() time.Duration {
+	if c.Stale <= 0 {
+		return DefaultStale
+	}
+	return c.Stale
+}
+
+func wrapDetail(inner []byte) []byte {
+	return bytes.Join([][]byte{[]byte("<detail>"), inner, []byte("</detail>")}, nil)
+}
+
+func unwrapDetail(detail []byte) []byte {
+	detail = bytes.TrimPrefix(detail, []byte("<detail>"))
+	return bytes.TrimSuffix(detail, []byte("</detail>"))
+}
+
+func lookup[T comparable](names map[T]string, name string) T {
+	for value, known := range names {
+		if strings.EqualFold(known, name) {
+			return value
+		}
+	}
+	var zero T
+	return zero
+}
+
+func newMessageID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
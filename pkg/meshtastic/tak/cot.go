@@ -0,0 +1,149 @@
+// Package tak converts ATAK plugin packets (proto.TAKPacket) to and from Cursor-on-Target
+// events and bridges them between the mesh and TAK clients or servers.
+package tak
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+)
+
+// TimeFormat is the layout of CoT timestamps.
+const TimeFormat = "2006-01-02T15:04:05.000Z"
+
+// Event types and how values used by ATAK.
+const (
+	TypeFriendlyGround = "a-f-G-U-C"
+	TypeGeoChat        = "b-t-f"
+	HowGPS             = "m-g"
+	HowHuman           = "h-g-i-g-o"
+)
+
+// unknownPoint is used for unknown height and errors of a point.
+const unknownPoint = 9999999.0
+
+// Event is a Cursor-on-Target event.
+type Event struct {
+	XMLName xml.Name `xml:"event"`
+	Version string   `xml:"version,attr"`
+	UID     string   `xml:"uid,attr"`
+	Type    string   `xml:"type,attr"`
+	Time    string   `xml:"time,attr"`
+	Start   string   `xml:"start,attr"`
+	Stale   string   `xml:"stale,attr"`
+	How     string   `xml:"how,attr"`
+	Point   Point    `xml:"point"`
+	Detail  Detail   `xml:"detail"`
+}
+
+// Point is the location of an event.
+type Point struct {
+	Lat float64 `xml:"lat,attr"`
+	Lon float64 `xml:"lon,attr"`
+	HAE float64 `xml:"hae,attr"`
+	CE  float64 `xml:"ce,attr"`
+	LE  float64 `xml:"le,attr"`
+}
+
+// MarshalXML writes the point without exponents, which some TAK clients can't parse.
+func (p Point) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = []xml.Attr{
+		{Name: xml.Name{Local: "lat"}, Value: formatNumber(p.Lat)},
+		{Name: xml.Name{Local: "lon"}, Value: formatNumber(p.Lon)},
+		{Name: xml.Name{Local: "hae"}, Value: formatNumber(p.HAE)},
+		{Name: xml.Name{Local: "ce"}, Value: formatNumber(p.CE)},
+		{Name: xml.Name{Local: "le"}, Value: formatNumber(p.LE)},
+	}
+	return e.EncodeElement(struct{}{}, start)
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Detail holds the event details known to the converter. Other elements are kept in Other.
+type Detail struct {
+	XMLName xml.Name       `xml:"detail"`
+	Contact *ContactDetail `xml:"contact"`
+	Group   *GroupDetail   `xml:"__group"`
+	Status  *StatusDetail  `xml:"status"`
+	Track   *TrackDetail   `xml:"track"`
+	UID     *UIDDetail     `xml:"uid"`
+	Chat    *ChatDetail    `xml:"__chat"`
+	Link    *LinkDetail    `xml:"link"`
+	Remarks *Remarks       `xml:"remarks"`
+	Other   []Element      `xml:",any"`
+}
+
+// ContactDetail is the <contact> detail.
+type ContactDetail struct {
+	Callsign string `xml:"callsign,attr"`
+	Endpoint string `xml:"endpoint,attr,omitempty"`
+}
+
+// GroupDetail is the <__group> detail with the team color and member role.
+type GroupDetail struct {
+	Name string `xml:"name,attr"`
+	Role string `xml:"role,attr"`
+}
+
+// StatusDetail is the <status> detail.
+type StatusDetail struct {
+	Battery uint32 `xml:"battery,attr"`
+}
+
+// TrackDetail is the <track> detail with speed in m/s and course in degrees.
+type TrackDetail struct {
+	Speed  float64 `xml:"speed,attr"`
+	Course float64 `xml:"course,attr"`
+}
+
+// UIDDetail is the <uid> detail.
+type UIDDetail struct {
+	Droid string `xml:"Droid,attr"`
+}
+
+// ChatDetail is the <__chat> detail of GeoChat messages.
+type ChatDetail struct {
+	Parent         string     `xml:"parent,attr,omitempty"`
+	GroupOwner     string     `xml:"groupOwner,attr,omitempty"`
+	MessageID      string     `xml:"messageId,attr,omitempty"`
+	Chatroom       string     `xml:"chatroom,attr"`
+	ID             string     `xml:"id,attr"`
+	SenderCallsign string     `xml:"senderCallsign,attr"`
+	Group          *ChatGroup `xml:"chatgrp"`
+}
+
+// ChatGroup lists the participants of a chat.
+type ChatGroup struct {
+	UID0 string `xml:"uid0,attr"`
+	UID1 string `xml:"uid1,attr"`
+	ID   string `xml:"id,attr"`
+}
+
+// LinkDetail is the <link> detail.
+type LinkDetail struct {
+	UID      string `xml:"uid,attr"`
+	Type     string `xml:"type,attr,omitempty"`
+	Relation string `xml:"relation,attr,omitempty"`
+}
+
+// Remarks is the <remarks> detail holding free text, like a chat message.
+type Remarks struct {
+	Source string `xml:"source,attr,omitempty"`
+	To     string `xml:"to,attr,omitempty"`
+	Time   string `xml:"time,attr,omitempty"`
+	Text   string `xml:",chardata"`
+}
+
+// Element is a detail element unknown to the converter.
+type Element struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   []byte     `xml:",innerxml"`
+}
+
+// FormatTime formats a CoT timestamp.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
@@ -0,0 +1,67 @@
+package tak
+
+import (
+	"fmt"
+	"net"
+)
+
+// MulticastEndpoint exchanges CoT events with ATAK clients on the local network over UDP
+// multicast. It is meant to be used as Input and Output of a Bridge.
+type MulticastEndpoint struct {
+	recv    *net.UDPConn
+	send    *net.UDPConn
+	buf     []byte
+	pending []byte
+}
+
+// ListenMulticast joins the multicast group at the address, usually DefaultMulticastAddress.
+// If iface is nil, the system chooses the network interface.
+func ListenMulticast(address string, iface *net.Interface) (*MulticastEndpoint, error) {
+	group, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid multicast address: %w", err)
+	}
+	recv, err := net.ListenMulticastUDP("udp4", iface, group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join multicast group: %w", err)
+	}
+	send, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		_ = recv.Close()
+		return nil, fmt.Errorf("failed to connect to multicast group: %w", err)
+	}
+	return &MulticastEndpoint{recv: recv, send: send, buf: make([]byte, 65536)}, nil
+}
+
+// Read reads CoT events sent to the group by others. Events written to the endpoint are
+// skipped, so the bridge does not send them back to the mesh.
+func (e *MulticastEndpoint) Read(b []byte) (int, error) {
+	local := e.send.LocalAddr().(*net.UDPAddr)
+	for len(e.pending) == 0 {
+		n, source, err := e.recv.ReadFromUDP(e.buf)
+		if err != nil {
+			return 0, err
+		}
+		if source.Port == local.Port && source.IP.Equal(local.IP) {
+			continue
+		}
+		e.pending = e.buf[:n]
+	}
+	n := copy(b, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+// Write sends a CoT event to the group. The event must fit into a datagram.
+func (e *MulticastEndpoint) Write(b []byte) (int, error) {
+	return e.send.Write(b)
+}
+
+// Close leaves the multicast group.
+func (e *MulticastEndpoint) Close() error {
+	err := e.recv.Close()
+	if sendErr := e.send.Close(); err == nil {
+		err = sendErr
+	}
+	return err
+}
@@ -0,0 +1,147 @@
+package tak
+
+import (
+	"fmt"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// compressedFields are the fields of a TAK packet compressed when is_compressed is set, by
+// field number. Nested messages list their compressed fields, strings have no entry. Like
+// the firmware, only callsigns and chat texts are compressed, raw details never are.
+//
+// Compressed strings are binary, which the generated types refuse, so packets are
+// compressed and decompressed in their wire format.
+var compressedFields = wireFields{
+	2: {1: nil, 2: nil},         // contact: callsign, device_callsign
+	6: {1: nil, 2: nil, 3: nil}, // chat: message, to, to_callsign
+}
+
+// isCompressedField is the number of the is_compressed field of TAK packets.
+const isCompressedField protowire.Number = 1
+
+type wireFields map[protowire.Number]wireFields
+
+// Unmarshal decodes a TAK packet payload, decompressing it if needed. The returned packet is
+// never compressed.
+func (c *Converter) Unmarshal(payload []byte) (*proto.TAKPacket, error) {
+	compressed, err := isCompressed(payload)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling error: %w", err)
+	}
+	if compressed {
+		payload, err = transcode(payload, compressedFields, func(s string) (string, error) {
+			text, err := c.codec().Decompress(s)
+			if err != nil {
+				return "", fmt.Errorf("failed to decompress TAK packet: %w", err)
+			}
+			return text, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	packet := new(proto.TAKPacket)
+	if err = protobuf.Unmarshal(payload, packet); err != nil {
+		return nil, fmt.Errorf("unmarshalling error: %w", err)
+	}
+	packet.IsCompressed = false
+	return packet, nil
+}
+
+// Marshal encodes a TAK packet as mesh packet payload. Packets too large for a mesh packet
+// are compressed like the firmware does, the result may still be too large.
+func (c *Converter) Marshal(packet *proto.TAKPacket) ([]byte, error) {
+	payload, err := protobuf.Marshal(packet)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling error: %w", err)
+	}
+	if len(payload) <= int(proto.Constants_DATA_PAYLOAD_LEN) || packet.GetIsCompressed() {
+		return payload, nil
+	}
+
+	compressed, err := transcode(payload, compressedFields, func(s string) (string, error) {
+		text, err := c.codec().Compress(s)
+		if err != nil {
+			return "", fmt.Errorf("failed to compress TAK packet: %w", err)
+		}
+		return text, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := protowire.AppendTag(nil, isCompressedField, protowire.VarintType)
+	result = protowire.AppendVarint(result, 1)
+	return append(result, compressed...), nil
+}
+
+// isCompressed reads the is_compressed field of an encoded TAK packet.
+func isCompressed(payload []byte) (bool, error) {
+	compressed := false
+	for len(payload) > 0 {
+		num, typ, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return false, protowire.ParseError(n)
+		}
+		payload = payload[n:]
+		if num == isCompressedField && typ == protowire.VarintType {
+			value, m := protowire.ConsumeVarint(payload)
+			if m < 0 {
+				return false, protowire.ParseError(m)
+			}
+			compressed = value != 0
+		}
+		if n = protowire.ConsumeFieldValue(num, typ, payload); n < 0 {
+			return false, protowire.ParseError(n)
+		}
+		payload = payload[n:]
+	}
+	return compressed, nil
+}
+
+// transcode converts the given string fields of an encoded message, copying other fields.
+func transcode(message []byte, fields wireFields, convert func(string) (string, error)) ([]byte, error) {
+	var result []byte
+	for len(message) > 0 {
+		num, typ, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			return nil, fmt.Errorf("unmarshalling error: %w", protowire.ParseError(n))
+		}
+		message = message[n:]
+
+		nested, ok := fields[num]
+		if !ok || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, message)
+			if n < 0 {
+				return nil, fmt.Errorf("unmarshalling error: %w", protowire.ParseError(n))
+			}
+			result = protowire.AppendTag(result, num, typ)
+			result = append(result, message[:n]...)
+			message = message[n:]
+			continue
+		}
+
+		value, n := protowire.ConsumeBytes(message)
+		if n < 0 {
+			return nil, fmt.Errorf("unmarshalling error: %w", protowire.ParseError(n))
+		}
+		message = message[n:]
+		var err error
+		if nested != nil {
+			value, err = transcode(value, nested, convert)
+		} else if len(value) > 0 {
+			var text string
+			text, err = convert(string(value))
+			value = []byte(text)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = protowire.AppendTag(result, num, protowire.BytesType)
+		result = protowire.AppendBytes(result, value)
+	}
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package tak
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func TestConverterMarshalSmall(t *testing.T) {
+	var c Converter
+	packet := &proto.TAKPacket{
+		Contact:        &proto.Contact{Callsign: "ALPHA-1", DeviceCallsign: "ANDROID-1"},
+		PayloadVariant: &proto.TAKPacket_Pli{Pli: &proto.PLI{LatitudeI: 521234567, LongitudeI: 43456789}},
+	}
+	payload, err := c.Marshal(packet)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	// packets which fit are sent as they are
+	if plain, _ := protobuf.Marshal(packet); string(payload) != string(plain) {
+		t.Errorf("small packet was changed: %x, want %x", payload, plain)
+	}
+	got, err := c.Unmarshal(payload)
+	if err != nil || !protobuf.Equal(got, packet) {
+		t.Errorf("unmarshalled %v (%v), want %v", got, err, packet)
+	}
+}
+
+func TestConverterMarshalCompressed(t *testing.T) {
+	var c Converter
+	to := "Team Cyan"
+	packet := &proto.TAKPacket{
+		Contact: &proto.Contact{Callsign: "Gräfin", DeviceCallsign: "ANDROID-5a3e6c1b2f4d7e90"},
+		Group:   &proto.Group{Team: proto.Team_Cyan, Role: proto.MemberRole_TeamLead},
+		PayloadVariant: &proto.TAKPacket_Chat{Chat: &proto.GeoChat{
+			Message: strings.Repeat("Roger that, moving to the rally point. ", 6),
+			To:      &to,
+		}},
+	}
+	payload, err := c.Marshal(packet)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if len(payload) > int(proto.Constants_DATA_PAYLOAD_LEN) {
+		t.Fatalf("compressed packet has %d bytes", len(payload))
+	}
+	if compressed, err := isCompressed(payload); err != nil || !compressed {
+		t.Fatalf("is_compressed is not set (%v)", err)
+	}
+
+	got, err := c.Unmarshal(payload)
+	if err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if !protobuf.Equal(got, packet) {
+		t.Errorf("unmarshalled %v, want %v", got, packet)
+	}
+}
+
+func TestConverterUnmarshalCompressed(t *testing.T) {
+	// a packet compressed by a sender: callsign "Hello World", device callsign "é"
+	contact := []byte{0x0a, 9}
+	contact = append(contact, mustHex(t, "8767c71483deb7c745")...)
+	contact = append(contact, 0x12, 3)
+	contact = append(contact, mustHex(t, "9e0549")...)
+	payload := []byte{0x08, 1, 0x12, byte(len(contact))}
+	payload = append(payload, contact...)
+	// raw details are not compressed
+	payload = append(payload, 0x3a, 5)
+	payload = append(payload, "<a/>\xff"...)
+
+	got, err := new(Converter).Unmarshal(payload)
+	if err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	want := &proto.TAKPacket{
+		Contact:        &proto.Contact{Callsign: "Hello World", DeviceCallsign: "é"},
+		PayloadVariant: &proto.TAKPacket_Detail{Detail: []byte("<a/>\xff")},
+	}
+	if !protobuf.Equal(got, want) {
+		t.Errorf("unmarshalled %v, want %v", got, want)
+	}
+}
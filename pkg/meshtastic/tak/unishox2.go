@@ -0,0 +1,856 @@
+package tak
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// ErrInvalidUnishox2 is returned when compressed data can not be decoded.
+var ErrInvalidUnishox2 = errors.New("invalid unishox2 data")
+
+// Unishox2 is the StringCodec used by the firmware and the ATAK plugin. It implements
+// Unishox2 with the default preset.
+//
+// Decompression handles everything the reference implementation produces. Compression
+// does not use the optional hex, UUID and template encodings, so its output may be
+// slightly longer, but it is decoded by any Unishox2 implementation.
+type Unishox2 struct{}
+
+var _ StringCodec = Unishox2{}
+
+// Compress compresses the string.
+func (Unishox2) Compress(s string) (string, error) {
+	return string(unishoxCompress([]byte(s))), nil
+}
+
+// Decompress decompresses the string.
+func (Unishox2) Decompress(s string) (string, error) {
+	out, err := unishoxDecompress([]byte(s))
+	return string(out), err
+}
+
+// character sets, selected by horizontal codes
+const (
+	usxAlpha = iota
+	usxSym
+	usxNum
+	usxDict
+	usxDelta
+)
+
+// usxSets holds the characters of the alpha, symbol and number sets by vertical code.
+// Zero entries are special codes.
+var usxSets = [3][28]byte{
+	{0, ' ', 'e', 't', 'a', 'o', 'i', 'n', 's', 'r', 'l', 'c', 'd', 'h', 'u', 'p', 'm', 'b',
+		'g', 'w', 'f', 'y', 'v', 'k', 'q', 'j', 'x', 'z'},
+	{'"', '{', '}', '_', '<', '>', ':', '\n', 0, '[', ']', '\\', ';', '\'', '\t', '@', '*', '&',
+		'?', '!', '^', '|', '\r', '~', '`', 0, 0, 0},
+	{0, ',', '.', '0', '1', '9', '2', '5', '-', '/', '3', '4', '6', '7', '8', '(', ')', ' ',
+		'=', '+', '$', '%', '#', 0, 0, 0, 0, 0},
+}
+
+// vertical codes select a character of a set, aligned to the most significant bit
+var (
+	usxVCodes = [28]byte{0x00, 0x40, 0x60, 0x80, 0x90, 0xA0, 0xB0, 0xC0, 0xD0, 0xD8, 0xE0, 0xE4,
+		0xE8, 0xEC, 0xEE, 0xF0, 0xF2, 0xF4, 0xF6, 0xF7, 0xF8, 0xF9, 0xFA, 0xFB, 0xFC, 0xFD, 0xFE, 0xFF}
+	usxVCodeLens = [28]int{2, 3, 3, 4, 4, 4, 4, 4, 5, 5, 6, 6, 6, 7, 7, 7, 7, 7, 8, 8, 8, 8, 8, 8,
+		8, 8, 8, 8}
+)
+
+// horizontal codes of the default preset
+var (
+	usxHCodes    = [5]byte{0x00, 0x40, 0x80, 0xC0, 0xE0}
+	usxHCodeLens = [5]int{2, 2, 2, 3, 3}
+)
+
+// frequent sequences of the default preset, coded by usxFreqCodes
+var (
+	usxFreqSeqs  = [6]string{"\": \"", "\": ", "</", "=\"", "\":\"", "://"}
+	usxFreqCodes = [6]int{usxSym<<5 | 25, usxSym<<5 | 26, usxSym<<5 | 27, usxNum<<5 | 23, usxNum<<5 | 24, usxNum<<5 | 25}
+)
+
+// usxTemplates are the templates of the default preset. 'f' and 'F' stand for hex digits,
+// 'r', 't' and 'o' for digits up to 7, 3 and 1.
+var usxTemplates = [4]string{"tfff-of-tfTtf:rf:rf.fffZ", "tfff-of-tf", "(fff) fff-ffff", "tf:rf:rf"}
+
+// special codes, as set<<5 | vertical code
+const (
+	usxLF       = usxSym<<5 | 7
+	usxCRLF     = usxSym<<5 | 8
+	usxTab      = usxSym<<5 | 14
+	usxCR       = usxSym<<5 | 22
+	usxNumSpace = usxNum<<5 | 17
+	usxRepeat   = usxNum<<5 | 26
+	usxTerm     = usxNum<<5 | 27
+)
+
+const (
+	// usxNiceLen is the minimum length of a repeated sequence
+	usxNiceLen = 5
+	// usxUniSpecial is the code of special characters in unicode delta mode
+	usxUniSpecial    = 0xF8
+	usxUniSpecialLen = 5
+)
+
+var (
+	usxCountBitLens = [5]int{2, 4, 7, 11, 16}
+	usxCountAdder   = [5]int{4, 20, 148, 2196, 67732}
+	usxUniBitLens   = [5]int{6, 12, 14, 16, 21}
+	usxUniAdder     = [5]int{0, 64, 4160, 20544, 86080}
+)
+
+// usxCodes holds codes of printable characters except space, as set<<5 | vertical code.
+var usxCodes = func() (codes [128]int) {
+	for set := range usxSets {
+		for v, c := range usxSets[set] {
+			if c <= ' ' {
+				continue
+			}
+			codes[c] = set<<5 | v
+			if c >= 'a' && c <= 'z' {
+				codes[c-'a'+'A'] = set<<5 | v
+			}
+		}
+	}
+	return codes
+}()
+
+type usxWriter struct {
+	out []byte
+	n   int
+}
+
+// bits writes the n most significant bits of the code.
+func (w *usxWriter) bits(code byte, n int) {
+	for i := 0; i < n; i++ {
+		if w.n%8 == 0 {
+			w.out = append(w.out, 0)
+		}
+		if code&(0x80>>i) != 0 {
+			w.out[len(w.out)-1] |= 0x80 >> (w.n % 8)
+		}
+		w.n++
+	}
+}
+
+// number writes the n least significant bits of the value.
+func (w *usxWriter) number(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.bits(byte(value>>i&1)<<7, 1)
+	}
+}
+
+// step writes idx one bits terminated by a zero bit, unless idx reaches the limit.
+func (w *usxWriter) step(idx, limit int) {
+	for i := 0; i < idx; i++ {
+		w.bits(0x80, 1)
+	}
+	if idx < limit {
+		w.bits(0, 1)
+	}
+}
+
+func (w *usxWriter) switchCode(state int) {
+	if state == usxDelta {
+		w.bits(usxUniSpecial, usxUniSpecialLen)
+		w.step(1, 4)
+		return
+	}
+	w.bits(0, 2)
+}
+
+func (w *usxWriter) set(set int) {
+	w.bits(usxHCodes[set], usxHCodeLens[set])
+}
+
+// code writes a character code, switching to its set first.
+func (w *usxWriter) code(code int, state *int) {
+	set, v := code>>5, code&0x1F
+	switch set {
+	case usxAlpha:
+		if *state != usxAlpha {
+			w.switchCode(*state)
+			w.set(usxAlpha)
+			*state = usxAlpha
+		}
+	case usxSym:
+		w.switchCode(*state)
+		w.set(usxSym)
+	case usxNum:
+		if *state != usxNum {
+			w.switchCode(*state)
+			w.set(usxNum)
+			// the number set is kept only for digits
+			if c := usxSets[set][v]; c >= '0' && c <= '9' {
+				*state = usxNum
+			}
+		}
+	}
+	w.bits(usxVCodes[v], usxVCodeLens[v])
+}
+
+func (w *usxWriter) count(count int) {
+	for i, adder := range usxCountAdder {
+		if count < adder {
+			w.step(i, 4)
+			if i > 0 {
+				count -= usxCountAdder[i-1]
+			}
+			w.number(count, usxCountBitLens[i])
+			return
+		}
+	}
+}
+
+// unicode writes the difference between the code point and the previous one.
+func (w *usxWriter) unicode(code, prev int) {
+	diff := code - prev
+	if diff < 0 {
+		diff = -diff
+	}
+	till := 0
+	for i, bitLen := range usxUniBitLens {
+		till += 1 << bitLen
+		if diff < till {
+			w.step(i, 5)
+			if prev > code {
+				w.bits(0x80, 1)
+			} else {
+				w.bits(0, 1)
+			}
+			w.number(diff-usxUniAdder[i], bitLen)
+			return
+		}
+	}
+}
+
+func unishoxCompress(in []byte) []byte {
+	w := new(usxWriter)
+	w.bits(0x80, 1) // magic bit
+	state := usxAlpha
+	allUpper := false
+	prevUni := 0
+
+	for l := 0; l < len(in); l++ {
+		if length, dist := usxFindRepeat(in, l); length > 0 {
+			w.switchCode(state)
+			w.set(usxDict)
+			w.count(length - usxNiceLen)
+			w.count(dist - usxNiceLen + 1)
+			l += length - 1
+			continue
+		}
+
+		c := in[l]
+		if l > 0 && l < len(in)-4 && c == in[l-1] && c == in[l+1] && c == in[l+2] && c == in[l+3] {
+			count := 4
+			for l+count < len(in) && in[l+count] == c {
+				count++
+			}
+			w.code(usxRepeat, &state)
+			w.count(count - 4)
+			l += count - 1
+			continue
+		}
+
+		if seq := usxFreqSeq(in[l:]); seq >= 0 {
+			w.code(usxFreqCodes[seq], &state)
+			l += len(usxFreqSeqs[seq]) - 1
+			continue
+		}
+
+		isUpper := c >= 'A' && c <= 'Z'
+		if allUpper && !isUpper {
+			allUpper = false
+			w.switchCode(state)
+			w.set(usxAlpha)
+			state = usxAlpha
+		}
+
+		switch {
+		case c >= ' ' && c <= '~':
+			if isUpper && !allUpper {
+				if state == usxNum {
+					w.switchCode(state)
+					w.set(usxAlpha)
+					state = usxAlpha
+				}
+				w.switchCode(state)
+				w.set(usxAlpha)
+				if state == usxDelta {
+					state = usxAlpha
+					w.switchCode(state)
+					w.set(usxAlpha)
+				}
+				if usxUpperRun(in[l:]) {
+					w.switchCode(state)
+					w.set(usxAlpha)
+					allUpper = true
+				}
+			}
+			if state == usxDelta && (c == ' ' || c == ',' || c == '.') {
+				w.bits(usxUniSpecial, usxUniSpecialLen)
+				switch c {
+				case ' ':
+					w.step(0, 4)
+				case ',':
+					w.step(2, 4)
+				case '.':
+					w.step(3, 4)
+				}
+				continue
+			}
+			if c != ' ' {
+				w.code(usxCodes[c], &state)
+			} else if state == usxNum {
+				w.bits(usxVCodes[usxNumSpace&0x1F], usxVCodeLens[usxNumSpace&0x1F])
+			} else {
+				w.bits(usxVCodes[1], usxVCodeLens[1])
+			}
+		case c == '\r' && l+1 < len(in) && in[l+1] == '\n':
+			w.code(usxCRLF, &state)
+			l++
+		case c == '\n' && state == usxDelta:
+			w.bits(usxUniSpecial, usxUniSpecialLen)
+			w.step(4, 4)
+		case c == '\n':
+			w.code(usxLF, &state)
+		case c == '\r':
+			w.code(usxCR, &state)
+		case c == '\t':
+			w.code(usxTab, &state)
+		case usxRuneLen(in[l:]) > 0:
+			r, size := utf8.DecodeRune(in[l:])
+			if state != usxDelta {
+				if usxRuneLen(in[l+size:]) > 0 {
+					// more non-ASCII characters follow, switch to delta coding
+					if state != usxAlpha {
+						w.switchCode(state)
+						w.set(usxAlpha)
+					}
+					w.switchCode(usxAlpha)
+					w.set(usxAlpha)
+					w.bits(usxVCodes[1], usxVCodeLens[1])
+					state = usxDelta
+				} else {
+					w.switchCode(state)
+					w.set(usxDelta)
+				}
+			}
+			w.unicode(int(r), prevUni)
+			prevUni = int(r)
+			l += size - 1
+		default:
+			count := 1
+			for l+count < len(in) && usxBinary(in[l+count:]) {
+				count++
+			}
+			w.switchCode(state)
+			w.set(usxNum)
+			w.bits(0, 2)
+			w.step(5, 5)
+			w.count(count)
+			for _, b := range in[l : l+count] {
+				w.bits(b, 8)
+			}
+			l += count - 1
+		}
+	}
+
+	// the terminator is only written to the unused bits of the last byte, decoding stops
+	// at the end of data anyway
+	if w.n%8 != 0 {
+		term := new(usxWriter)
+		if state != usxNum {
+			term.switchCode(state)
+			term.set(usxNum)
+		}
+		term.bits(usxVCodes[usxTerm&0x1F], usxVCodeLens[usxTerm&0x1F])
+		for i := 0; w.n%8 != 0; i++ {
+			bit := byte(0)
+			if i < term.n {
+				bit = term.out[i/8] << (i % 8) & 0x80
+			}
+			w.bits(bit, 1)
+		}
+	}
+	return w.out
+}
+
+// usxFindRepeat finds the longest earlier occurrence of the data at l. It returns the length
+// and the distance of the occurrence, zero if there is none worth coding.
+func usxFindRepeat(in []byte, l int) (length, dist int) {
+	if l >= len(in)-usxNiceLen+1 {
+		return 0, 0
+	}
+	for j := l - usxNiceLen; j >= 0; j-- {
+		k := l
+		for k < len(in) && j+k-l < l && in[k] == in[j+k-l] {
+			k++
+		}
+		// don't split UTF-8 sequences
+		for k > l && k < len(in) && in[k]>>6 == 2 {
+			k--
+		}
+		if k-l > usxNiceLen && k-l > length {
+			length, dist = k-l, l-j
+		}
+	}
+	return length, dist
+}
+
+// usxFreqSeq returns the index of the frequent sequence the data starts with, -1 if none.
+func usxFreqSeq(in []byte) int {
+	for i, seq := range usxFreqSeqs {
+		if len(in) >= len(seq) && string(in[:len(seq)]) == seq {
+			return i
+		}
+	}
+	return -1
+}
+
+// usxUpperRun reports whether the data starts with six capital letters.
+func usxUpperRun(in []byte) bool {
+	if len(in) < 6 {
+		return false
+	}
+	for _, c := range in[:6] {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// usxRuneLen returns the length of the multi-byte UTF-8 character the data starts with,
+// zero if there is none.
+func usxRuneLen(in []byte) int {
+	if len(in) == 0 || in[0] < utf8.RuneSelf {
+		return 0
+	}
+	r, size := utf8.DecodeRune(in)
+	if r == utf8.RuneError && size == 1 {
+		return 0
+	}
+	return size
+}
+
+// usxBinary reports whether the data starts with a byte which is coded as binary.
+func usxBinary(in []byte) bool {
+	c := in[0]
+	switch {
+	case c >= ' ' && c <= '~', c == '\r', c == '\n', c == '\t':
+		return false
+	case c < utf8.RuneSelf:
+		return true
+	default:
+		return usxRuneLen(in) == 0
+	}
+}
+
+type usxReader struct {
+	in  []byte
+	pos int
+	len int
+}
+
+// peek returns the next 8 bits, padded with ones after the end of data.
+func (r *usxReader) peek() byte {
+	i, shift := r.pos/8, r.pos%8
+	code := r.in[i] << shift
+	if i+1 < len(r.in) {
+		code |= r.in[i+1] >> (8 - shift)
+	} else {
+		code |= 0xFF >> (8 - shift)
+	}
+	return code
+}
+
+func (r *usxReader) bit() bool {
+	return r.in[r.pos/8]&(0x80>>(r.pos%8)) != 0
+}
+
+// number reads an n bit number, -1 if there are not enough bits.
+func (r *usxReader) number(n int) int {
+	if r.pos+n > r.len {
+		return -1
+	}
+	value := 0
+	for i := 0; i < n; i++ {
+		value <<= 1
+		if r.bit() {
+			value |= 1
+		}
+		r.pos++
+	}
+	return value
+}
+
+// vcode reads a vertical code, -1 at the end of data.
+func (r *usxReader) vcode() int {
+	if r.pos >= r.len {
+		return -1
+	}
+	code := r.peek()
+	for v, vcode := range usxVCodes {
+		n := usxVCodeLens[v]
+		if code&(0xFF<<(8-n)) == vcode {
+			if r.pos+n > r.len {
+				return -1
+			}
+			r.pos += n
+			return v
+		}
+	}
+	return -1
+}
+
+// set reads a horizontal code, -1 at the end of data.
+func (r *usxReader) set() int {
+	if r.pos >= r.len {
+		return -1
+	}
+	code := r.peek()
+	for set, hcode := range usxHCodes {
+		if code&(0xFF<<(8-usxHCodeLens[set])) == hcode {
+			r.pos += usxHCodeLens[set]
+			return set
+		}
+	}
+	return -1
+}
+
+// step reads one bits terminated by a zero bit or the limit, -1 at the end of data.
+func (r *usxReader) step(limit int) int {
+	idx := 0
+	for r.pos < r.len && r.bit() {
+		idx++
+		r.pos++
+		if idx == limit {
+			return idx
+		}
+	}
+	if r.pos >= r.len {
+		return -1
+	}
+	r.pos++
+	return idx
+}
+
+func (r *usxReader) count() int {
+	idx := r.step(4)
+	if idx < 0 {
+		return -1
+	}
+	count := r.number(usxCountBitLens[idx])
+	if count < 0 {
+		return -1
+	}
+	if idx > 0 {
+		count += usxCountAdder[idx-1]
+	}
+	return count
+}
+
+// unicode reads a code point difference. Special codes are returned as special, -1 if
+// the difference is read. ok is false at the end of data.
+func (r *usxReader) unicode() (diff, special int, ok bool) {
+	idx := r.step(5)
+	if idx < 0 {
+		return 0, 0, false
+	}
+	if idx == 5 {
+		special = r.step(4)
+		return 0, special, special >= 0
+	}
+	if r.pos >= r.len {
+		return 0, 0, false
+	}
+	negative := r.bit()
+	r.pos++
+	diff = r.number(usxUniBitLens[idx])
+	if diff < 0 {
+		return 0, 0, false
+	}
+	diff += usxUniAdder[idx]
+	if negative {
+		diff = -diff
+	}
+	return diff, -1, true
+}
+
+// repeat copies an earlier sequence of the output.
+func (r *usxReader) repeat(out []byte) ([]byte, error) {
+	length, dist := r.count(), r.count()
+	if length < 0 || dist < 0 {
+		return nil, ErrInvalidUnishox2
+	}
+	length += usxNiceLen
+	dist += usxNiceLen - 1
+	if dist > len(out) {
+		return nil, ErrInvalidUnishox2
+	}
+	start := len(out) - dist
+	for i := 0; i < length; i++ {
+		out = append(out, out[start+i])
+	}
+	return out, nil
+}
+
+// special reads templates, hex numbers and binary data. It returns false at the end of data.
+func (r *usxReader) special(out []byte) ([]byte, bool) {
+	idx := r.step(5)
+	switch {
+	case idx < 0:
+		return out, false
+	case idx == 0:
+		template := r.step(4)
+		remaining := r.count()
+		if template < 0 || template >= len(usxTemplates) || remaining < 0 || remaining > len(usxTemplates[template]) {
+			return out, false
+		}
+		for _, c := range []byte(usxTemplates[template][:len(usxTemplates[template])-remaining]) {
+			bits := 0
+			switch c {
+			case 'f', 'F':
+				bits = 4
+			case 'r':
+				bits = 3
+			case 't':
+				bits = 2
+			case 'o':
+				bits = 1
+			default:
+				out = append(out, c)
+				continue
+			}
+			nibble := r.number(bits)
+			if nibble < 0 {
+				return out, false
+			}
+			out = append(out, usxHexChar(nibble, c == 'f'))
+		}
+	case idx == 5:
+		count := r.count()
+		if count <= 0 {
+			return out, false
+		}
+		for ; count > 0; count-- {
+			b := r.number(8)
+			if b < 0 {
+				return out, false
+			}
+			out = append(out, byte(b))
+		}
+	default:
+		// 1 and 3 are lower and upper case hex numbers, 2 and 4 UUIDs
+		count := 32
+		if idx != 2 && idx != 4 {
+			if count = r.count(); count <= 0 {
+				return out, false
+			}
+		}
+		for ; count > 0; count-- {
+			nibble := r.number(4)
+			if nibble < 0 {
+				return out, false
+			}
+			out = append(out, usxHexChar(nibble, idx < 3))
+			if (idx == 2 || idx == 4) && (count == 25 || count == 21 || count == 17 || count == 13) {
+				out = append(out, '-')
+			}
+		}
+	}
+	return out, true
+}
+
+func usxHexChar(nibble int, lower bool) byte {
+	switch {
+	case nibble < 10:
+		return byte('0' + nibble)
+	case lower:
+		return byte('a' + nibble - 10)
+	default:
+		return byte('A' + nibble - 10)
+	}
+}
+
+func unishoxDecompress(in []byte) ([]byte, error) {
+	r := &usxReader{in: in, pos: 1, len: len(in) * 8} // skip the magic bit
+	var out []byte
+	state, set := usxAlpha, usxAlpha
+	allUpper := false
+	prevUni := 0
+
+	var err error
+decode:
+	for r.pos < r.len {
+		if state == usxDelta || set == usxDelta {
+			if state != usxDelta {
+				set = state
+			}
+			diff, special, ok := r.unicode()
+			if !ok {
+				break
+			}
+			switch special {
+			case -1:
+				prevUni += diff
+				if prevUni < 0 || prevUni > utf8.MaxRune {
+					return nil, ErrInvalidUnishox2
+				}
+				out = utf8.AppendRune(out, rune(prevUni))
+			case 0:
+				out = append(out, ' ')
+				continue
+			case 1:
+				set = r.set()
+				switch set {
+				case -1:
+					break decode
+				case usxAlpha, usxDelta:
+					state = set
+					continue
+				case usxDict:
+					if out, err = r.repeat(out); err != nil {
+						return nil, err
+					}
+					set = state
+					continue
+				}
+				// a symbol or number follows
+			case 2:
+				out = append(out, ',')
+				continue
+			case 3:
+				out = append(out, '.')
+				continue
+			case 4:
+				out = append(out, '\n')
+				continue
+			}
+			if state == usxDelta && set == usxDelta {
+				continue
+			}
+		} else {
+			set = state
+		}
+
+		isUpper := allUpper
+		v := r.vcode()
+		if v < 0 || set < 0 {
+			break
+		}
+		if v == 0 && set != usxSym {
+			if r.pos >= r.len {
+				break
+			}
+			// in delta mode the switch code is read with the unicode special code already
+			if set != usxNum || state != usxDelta {
+				if set = r.set(); set < 0 || r.pos >= r.len {
+					break
+				}
+			}
+			switch set {
+			case usxAlpha:
+				if state != usxAlpha {
+					state = usxAlpha
+					continue
+				}
+				if allUpper {
+					allUpper = false
+					continue
+				}
+				if v = r.vcode(); v < 0 {
+					break decode
+				}
+				if v == 0 {
+					if set = r.set(); set != usxAlpha {
+						return nil, ErrInvalidUnishox2
+					}
+					allUpper = true
+					continue
+				}
+				isUpper = true
+			case usxDict:
+				if out, err = r.repeat(out); err != nil {
+					return nil, err
+				}
+				continue
+			case usxDelta:
+				continue
+			default:
+				if set != usxNum || state != usxDelta {
+					v = r.vcode()
+				}
+				if v < 0 {
+					break decode
+				}
+				if set == usxNum && v == 0 {
+					var ok bool
+					if out, ok = r.special(out); !ok {
+						break decode
+					}
+					if state == usxDelta {
+						set = usxDelta
+					}
+					continue
+				}
+			}
+		}
+
+		if isUpper && v == 1 {
+			// continuous delta coding of non-ASCII characters
+			state, set = usxDelta, usxDelta
+			continue
+		}
+		c := usxSets[set][v]
+		switch {
+		case c >= 'a' && c <= 'z':
+			state = usxAlpha
+			if isUpper {
+				c -= 'a' - 'A'
+			}
+		case c >= '0' && c <= '9':
+			state = usxNum
+		case c == 0:
+			code := set<<5 | v
+			switch {
+			case code == usxCRLF:
+				out = append(out, '\r', '\n')
+			case code == usxRepeat:
+				count := r.count()
+				if count < 0 {
+					break decode
+				}
+				if len(out) == 0 {
+					return nil, ErrInvalidUnishox2
+				}
+				last := out[len(out)-1]
+				for i := 0; i < count+4; i++ {
+					out = append(out, last)
+				}
+			case code == usxTerm:
+				break decode
+			default:
+				seq := 0
+				for seq < len(usxFreqCodes) && usxFreqCodes[seq] != code {
+					seq++
+				}
+				if seq == len(usxFreqCodes) {
+					return nil, ErrInvalidUnishox2
+				}
+				out = append(out, usxFreqSeqs[seq]...)
+			}
+			if state == usxDelta {
+				set = usxDelta
+			}
+			continue
+		}
+		if state == usxDelta {
+			set = usxDelta
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
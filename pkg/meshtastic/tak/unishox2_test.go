@@ -0,0 +1,110 @@
+package tak
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid hex %q: %v", s, err)
+	}
+	return b
+}
+
+// unishoxVectors are coded bit by bit from the default preset tables: the magic bit, then
+// the codes of the characters, then as much of the terminator as fits into the last byte.
+var unishoxVectors = []struct {
+	text       string
+	compressed string
+}{
+	// H: upper case prefix 00 00, h 1110110; e 011; l 111000; o 1010; space 010; ...
+	{text: "Hello World", compressed: "8767c71483deb7c745"},
+	// a 1001; -: switch 00, number set 10, 11010; 1: 00 10 1001, the number set is kept;
+	// the terminator 11111111 is cut to 2 bits
+	{text: "a-1", compressed: "c968a7"},
+	// switch 00, delta set 111, step code 10, sign 0, 233-64 in 12 bits; terminator 001
+	{text: "é", compressed: "9e0549"},
+}
+
+func TestUnishox2Vectors(t *testing.T) {
+	var codec Unishox2
+	for _, tt := range unishoxVectors {
+		compressed, err := codec.Compress(tt.text)
+		if err != nil {
+			t.Fatalf("failed to compress %q: %v", tt.text, err)
+		}
+		if got := hex.EncodeToString([]byte(compressed)); got != tt.compressed {
+			t.Errorf("compressed %q to %s, want %s", tt.text, got, tt.compressed)
+		}
+		text, err := codec.Decompress(string(mustHex(t, tt.compressed)))
+		if err != nil || text != tt.text {
+			t.Errorf("decompressed %s to %q (%v), want %q", tt.compressed, text, err, tt.text)
+		}
+	}
+}
+
+func TestUnishox2DecompressHex(t *testing.T) {
+	// switch 00, number set 10, special 00, lower case hex 10, count 8 as 10 0100, nibbles,
+	// terminator 0; the compressor does not produce it, the reference implementation does
+	text, err := Unishox2{}.Decompress(string(mustHex(t, "9149bd5b7dde")))
+	if err != nil || text != "deadbeef" {
+		t.Errorf("decompressed %q (%v), want deadbeef", text, err)
+	}
+}
+
+func TestUnishox2RoundTrip(t *testing.T) {
+	texts := []string{
+		"",
+		"ALPHA-1",
+		"ANDROID-5a3e6c1b2f4d7e90",
+		"Team Lead",
+		"Roger that, moving to the rally point at 1400.",
+		"HELLO EVERYONE, radio check",
+		"aaaaaaaaaaaa",
+		"test 12345 test 12345 test 12345",
+		"{\"lat\": \"52.1\",\"lon\":\"4.3\"} https://example.com </a>",
+		"line\r\nnext\nlast\ttab\r",
+		"Привет, как дела?",
+		"日本語のテキスト。",
+		"Grüße aus Köln 😀",
+		"\x00\x01binary\xff\xfe",
+	}
+	rng := rand.New(rand.NewSource(1))
+	const alphabet = "aAbZ 09.,-\n\r\t\"{}:/<=é€"
+	for i := 0; i < 1000; i++ {
+		text := make([]byte, rng.Intn(40))
+		for j := range text {
+			if rng.Intn(4) == 0 {
+				text[j] = byte(rng.Intn(256))
+			} else {
+				text[j] = alphabet[rng.Intn(len(alphabet))]
+			}
+		}
+		texts = append(texts, string(text))
+	}
+
+	var codec Unishox2
+	for _, text := range texts {
+		compressed, err := codec.Compress(text)
+		if err != nil {
+			t.Fatalf("failed to compress %q: %v", text, err)
+		}
+		decompressed, err := codec.Decompress(compressed)
+		if err != nil || decompressed != text {
+			t.Fatalf("round trip of %q gave %q (%v)", text, decompressed, err)
+		}
+	}
+}
+
+func TestUnishox2DecompressGarbage(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 10000; i++ {
+		data := make([]byte, rng.Intn(30))
+		rng.Read(data)
+		_, _ = Unishox2{}.Decompress(string(data)) // must not panic
+	}
+}
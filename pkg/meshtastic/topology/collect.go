@@ -0,0 +1,149 @@
+package topology
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// snrScale is the scale of SNR values in RouteDiscovery messages.
+const snrScale = 4
+
+// unknownSNR marks a hop whose SNR was not measured in RouteDiscovery messages.
+const unknownSNR = -128
+
+// Run feeds packets received by the local node into the graph until the context is cancelled
+// or the receiver fails.
+func (g *Graph) Run(ctx context.Context, localNode uint32, receiver meshtastic.PacketReceiver) error {
+	for {
+		packet, err := receiver.ReceiveFromMesh(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		g.Observe(localNode, packet)
+	}
+}
+
+// Observe learns links from a packet heard by the receiver node.
+func (g *Graph) Observe(receiver uint32, packet *proto.MeshPacket) {
+	at := g.now()
+	if packet.GetRxTime() != 0 {
+		at = time.Unix(int64(packet.GetRxTime()), 0)
+	}
+	g.observeReception(receiver, packet, at)
+
+	decoded := packet.GetDecoded()
+	switch decoded.GetPortnum() {
+	case proto.PortNum_NEIGHBORINFO_APP:
+		info := new(proto.NeighborInfo)
+		if protobuf.Unmarshal(decoded.GetPayload(), info) == nil {
+			g.AddNeighborInfo(info, at)
+		}
+	case proto.PortNum_TRACEROUTE_APP:
+		route := new(proto.RouteDiscovery)
+		if decoded.GetRequestId() != 0 && protobuf.Unmarshal(decoded.GetPayload(), route) == nil {
+			g.AddTraceroute(packet.GetTo(), packet.GetFrom(), route, at)
+		}
+	case proto.PortNum_POSITION_APP:
+		pos := new(proto.Position)
+		if protobuf.Unmarshal(decoded.GetPayload(), pos) == nil && pos.LatitudeI != nil && pos.LongitudeI != nil {
+			g.SetPosition(packet.GetFrom(), Position{
+				LatitudeI:  pos.GetLatitudeI(),
+				LongitudeI: pos.GetLongitudeI(),
+				Altitude:   pos.GetAltitude(),
+			})
+		}
+	case proto.PortNum_NODEINFO_APP:
+		user := new(proto.User)
+		if protobuf.Unmarshal(decoded.GetPayload(), user) == nil && user.GetLongName() != "" {
+			g.SetName(packet.GetFrom(), user.GetLongName())
+		}
+	}
+}
+
+// observeReception learns the last hop of a packet from its hop counters and relay node.
+func (g *Graph) observeReception(receiver uint32, packet *proto.MeshPacket, at time.Time) {
+	if packet.GetViaMqtt() || packet.GetRxSnr() == 0 && packet.GetRxRssi() == 0 {
+		return // not received over radio
+	}
+
+	if packet.GetHopStart() != 0 && packet.GetHopStart() == packet.GetHopLimit() {
+		g.AddLink(packet.GetFrom(), receiver, packet.GetRxSnr(), SourceDirectPacket, at)
+		return
+	}
+
+	if relay := packet.GetRelayNode(); relay != 0 {
+		if relayer, ok := g.resolveRelay(receiver, relay); ok {
+			g.AddLink(relayer, receiver, packet.GetRxSnr(), SourceRelayedPacket, at)
+		}
+	}
+}
+
+// resolveRelay finds the node whose number ends with the relay byte. Known neighbors of the
+// receiver are preferred. Ambiguous matches are not resolved.
+func (g *Graph) resolveRelay(receiver, relay uint32) (uint32, bool) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	var neighbors, others []uint32
+	for node := range g.nodes {
+		if node&0xff != relay&0xff || node == receiver {
+			continue
+		}
+		if _, ok := g.links[linkKey{node, receiver}]; ok {
+			neighbors = append(neighbors, node)
+		} else {
+			others = append(others, node)
+		}
+	}
+	switch {
+	case len(neighbors) == 1:
+		return neighbors[0], true
+	case len(neighbors) == 0 && len(others) == 1:
+		return others[0], true
+	default:
+		return 0, false
+	}
+}
+
+// AddNeighborInfo records links reported by a node's neighbor info module.
+func (g *Graph) AddNeighborInfo(info *proto.NeighborInfo, at time.Time) {
+	for _, neighbor := range info.GetNeighbors() {
+		seen := at
+		if neighbor.GetLastRxTime() != 0 {
+			seen = time.Unix(int64(neighbor.GetLastRxTime()), 0)
+		}
+		g.AddLink(neighbor.GetNodeId(), info.GetNodeId(), neighbor.GetSnr(), SourceNeighborInfo, seen)
+	}
+}
+
+// AddTraceroute records links from a traceroute reply sent by the destination to the origin.
+func (g *Graph) AddTraceroute(origin, destination uint32, route *proto.RouteDiscovery, at time.Time) {
+	towards := append(append([]uint32{origin}, route.GetRoute()...), destination)
+	g.addRoute(towards, route.GetSnrTowards(), at)
+
+	if len(route.GetSnrBack()) > 0 {
+		back := append(append([]uint32{destination}, route.GetRouteBack()...), origin)
+		g.addRoute(back, route.GetSnrBack(), at)
+	}
+}
+
+// addRoute records links between consecutive hops. snr[i] is measured by hops[i+1].
+func (g *Graph) addRoute(hops []uint32, snr []int32, at time.Time) {
+	for i := 0; i+1 < len(hops); i++ {
+		if i >= len(snr) || snr[i] == unknownSNR {
+			continue
+		}
+		if hops[i] == meshtastic.BroadcastNodenum || hops[i+1] == meshtastic.BroadcastNodenum {
+			continue // hop which did not report itself
+		}
+		g.AddLink(hops[i], hops[i+1], float32(snr[i])/snrScale, SourceTraceroute, at)
+	}
+}
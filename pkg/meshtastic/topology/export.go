@@ -0,0 +1,109 @@
+package topology
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/geojson"
+)
+
+// WriteDOT writes the graph in Graphviz DOT format. Edge labels show SNR, and edge
+// thickness follows the link confidence.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	g.lock.RLock()
+	links := g.liveLinks()
+	names := make(map[uint32]string, len(g.names))
+	for node, name := range g.names {
+		names[node] = name
+	}
+	nodes := make([]uint32, 0, len(g.nodes))
+	for node := range g.nodes {
+		nodes = append(nodes, node)
+	}
+	g.lock.RUnlock()
+
+	slices.Sort(nodes)
+	slices.SortFunc(links, compareLinks)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph mesh {")
+	for _, node := range nodes {
+		label := meshtastic.FormatNodeID(node)
+		if name, ok := names[node]; ok && name != "" {
+			label = fmt.Sprintf("%s\\n%s", name, label)
+		}
+		fmt.Fprintf(bw, "  %q [label=%q];\n", meshtastic.FormatNodeID(node), label)
+	}
+	for _, link := range links {
+		fmt.Fprintf(bw, "  %q -> %q [label=\"%.1f dB\", penwidth=%.2f];\n",
+			meshtastic.FormatNodeID(link.From), meshtastic.FormatNodeID(link.To),
+			link.SNR, 0.5+2.5*link.Confidence)
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// GeoJSON exports nodes with known positions as points, and links between them as lines.
+func (g *Graph) GeoJSON() geojson.FeatureCollection {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	var features []geojson.Feature
+	nodes := make([]uint32, 0, len(g.positions))
+	for node := range g.positions {
+		nodes = append(nodes, node)
+	}
+	slices.Sort(nodes)
+	for _, node := range nodes {
+		feature := geojson.NewFeature(geojson.Point(position(g.positions[node])), map[string]any{
+			"id":   meshtastic.FormatNodeID(node),
+			"name": g.names[node],
+		})
+		feature.ID = meshtastic.FormatNodeID(node)
+		features = append(features, feature)
+	}
+
+	links := g.liveLinks()
+	slices.SortFunc(links, compareLinks)
+	for _, link := range links {
+		from, ok := g.positions[link.From]
+		if !ok {
+			continue
+		}
+		to, ok := g.positions[link.To]
+		if !ok {
+			continue
+		}
+		features = append(features, geojson.NewFeature(geojson.LineString(position(from), position(to)), map[string]any{
+			"from":       meshtastic.FormatNodeID(link.From),
+			"to":         meshtastic.FormatNodeID(link.To),
+			"snr":        link.SNR,
+			"confidence": link.Confidence,
+			"source":     link.Source.String(),
+		}))
+	}
+	return geojson.NewFeatureCollection(features...)
+}
+
+func position(pos Position) geojson.Position {
+	return geojson.PositionI(pos.LatitudeI, pos.LongitudeI, pos.Altitude)
+}
+
+func compareLinks(a, b Link) int {
+	if a.From != b.From {
+		if a.From < b.From {
+			return -1
+		}
+		return 1
+	}
+	if a.To < b.To {
+		return -1
+	}
+	if a.To > b.To {
+		return 1
+	}
+	return 0
+}
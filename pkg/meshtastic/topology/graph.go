@@ -0,0 +1,213 @@
+// Package topology builds a graph of radio links between mesh nodes from neighbor info,
+// traceroute results and reception details of ordinary packets.
+package topology
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHalfLife is the default time after which the confidence in a link halves.
+	DefaultHalfLife = time.Hour
+	// minConfidence is the confidence below which a link is considered gone.
+	minConfidence = 0.05
+	// snrSmoothing is the weight of a new SNR sample in the link's moving average.
+	snrSmoothing = 0.3
+)
+
+// Source identifies the kind of observation a link was learned from.
+type Source int
+
+const (
+	SourceNeighborInfo Source = iota
+	SourceTraceroute
+	SourceDirectPacket
+	SourceRelayedPacket
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceNeighborInfo:
+		return "neighborinfo"
+	case SourceTraceroute:
+		return "traceroute"
+	case SourceDirectPacket:
+		return "direct"
+	case SourceRelayedPacket:
+		return "relay"
+	default:
+		return "unknown"
+	}
+}
+
+// Link is a directed radio link: packets transmitted by From are heard by To.
+type Link struct {
+	From, To uint32
+	// SNR is the smoothed signal-to-noise ratio measured by the receiving node, in dB.
+	SNR float32
+	// LastSeen is the time of the latest observation.
+	LastSeen time.Time
+	// Observations is the number of times the link was observed.
+	Observations int
+	// Source is the kind of the latest observation.
+	Source Source
+	// Confidence is the time-decayed confidence that the link still exists, from 0 to 1.
+	Confidence float64
+}
+
+// Cost returns the routing cost of the link. Good and recently seen links are cheaper.
+func (l Link) Cost() float64 {
+	return snrCost(l.SNR) / l.Confidence
+}
+
+// snrCost maps SNR to a cost between 1 (excellent link) and 10 (link at the noise floor).
+func snrCost(snr float32) float64 {
+	quality := (float64(snr) + 20) / 30 // -20 dB is about the demodulation limit of slow presets
+	quality = math.Max(0.1, math.Min(1, quality))
+	return 1 / quality
+}
+
+// Position is the location of a node in integer coordinates (1e-7 degrees).
+type Position struct {
+	LatitudeI, LongitudeI, Altitude int32
+}
+
+type linkKey struct {
+	from, to uint32
+}
+
+// Graph is a time-decayed weighted graph of mesh links. It is safe for concurrent use.
+type Graph struct {
+	// HalfLife is the time after which the confidence in an unobserved link halves.
+	// Defaults to DefaultHalfLife.
+	HalfLife time.Duration
+	// Now returns current time. Defaults to time.Now.
+	Now func() time.Time
+
+	lock      sync.RWMutex
+	links     map[linkKey]*Link
+	nodes     map[uint32]struct{}
+	positions map[uint32]Position
+	names     map[uint32]string
+}
+
+// AddLink records an observation of a link.
+func (g *Graph) AddLink(from, to uint32, snr float32, source Source, at time.Time) {
+	if from == to || from == 0 || to == 0 {
+		return
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.init()
+
+	g.nodes[from] = struct{}{}
+	g.nodes[to] = struct{}{}
+	key := linkKey{from, to}
+	link, ok := g.links[key]
+	if !ok {
+		g.links[key] = &Link{From: from, To: to, SNR: snr, LastSeen: at, Observations: 1, Source: source}
+		return
+	}
+
+	link.SNR = float32(snrSmoothing)*snr + float32(1-snrSmoothing)*link.SNR
+	link.Observations++
+	link.Source = source
+	if at.After(link.LastSeen) {
+		link.LastSeen = at
+	}
+}
+
+// SetPosition records the location of a node.
+func (g *Graph) SetPosition(node uint32, pos Position) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.init()
+	g.nodes[node] = struct{}{}
+	g.positions[node] = pos
+}
+
+// SetName records a human-readable name of a node, used in exports.
+func (g *Graph) SetName(node uint32, name string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.init()
+	g.nodes[node] = struct{}{}
+	g.names[node] = name
+}
+
+// Links returns all links whose confidence is above the pruning threshold.
+func (g *Graph) Links() []Link {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.liveLinks()
+}
+
+// Nodes returns all known node numbers.
+func (g *Graph) Nodes() []uint32 {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	nodes := make([]uint32, 0, len(g.nodes))
+	for node := range g.nodes {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// Prune removes links whose confidence decayed below the threshold.
+func (g *Graph) Prune() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	now := g.now()
+	for key, link := range g.links {
+		if g.confidence(link, now) < minConfidence {
+			delete(g.links, key)
+		}
+	}
+}
+
+// liveLinks returns copies of links with their current confidence. The lock must be held.
+func (g *Graph) liveLinks() []Link {
+	now := g.now()
+	links := make([]Link, 0, len(g.links))
+	for _, link := range g.links {
+		confidence := g.confidence(link, now)
+		if confidence < minConfidence {
+			continue
+		}
+		l := *link
+		l.Confidence = confidence
+		links = append(links, l)
+	}
+	return links
+}
+
+func (g *Graph) confidence(link *Link, now time.Time) float64 {
+	halfLife := g.HalfLife
+	if halfLife <= 0 {
+		halfLife = DefaultHalfLife
+	}
+	age := now.Sub(link.LastSeen)
+	if age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+func (g *Graph) now() time.Time {
+	if g.Now != nil {
+		return g.Now()
+	}
+	return time.Now()
+}
+
+func (g *Graph) init() {
+	if g.links == nil {
+		g.links = make(map[linkKey]*Link)
+		g.nodes = make(map[uint32]struct{})
+		g.positions = make(map[uint32]Position)
+		g.names = make(map[uint32]string)
+	}
+}
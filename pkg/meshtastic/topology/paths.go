@@ -0,0 +1,140 @@
+package topology
+
+import (
+	"container/heap"
+	"slices"
+)
+
+// BestPath finds the cheapest path for packets sent from one node to another.
+// It returns the nodes along the path, including both ends, and the total cost.
+func (g *Graph) BestPath(from, to uint32) ([]uint32, float64, bool) {
+	g.lock.RLock()
+	links := g.liveLinks()
+	g.lock.RUnlock()
+
+	adjacent := make(map[uint32][]Link)
+	for _, link := range links {
+		adjacent[link.From] = append(adjacent[link.From], link)
+	}
+
+	dist := map[uint32]float64{from: 0}
+	prev := make(map[uint32]uint32)
+	queue := &pathQueue{{node: from}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(pathItem)
+		if item.cost > dist[item.node] {
+			continue // stale queue entry
+		}
+		if item.node == to {
+			break
+		}
+		for _, link := range adjacent[item.node] {
+			cost := item.cost + link.Cost()
+			if known, ok := dist[link.To]; ok && known <= cost {
+				continue
+			}
+			dist[link.To] = cost
+			prev[link.To] = item.node
+			heap.Push(queue, pathItem{node: link.To, cost: cost})
+		}
+	}
+
+	cost, ok := dist[to]
+	if !ok {
+		return nil, 0, false
+	}
+	path := []uint32{to}
+	for node := to; node != from; {
+		node = prev[node]
+		path = append(path, node)
+	}
+	slices.Reverse(path)
+	return path, cost, true
+}
+
+type pathItem struct {
+	node uint32
+	cost float64
+}
+
+type pathQueue []pathItem
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q pathQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)        { *q = append(*q, x.(pathItem)) }
+func (q *pathQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// ArticulationNodes returns nodes whose failure would split the mesh into disconnected parts.
+// Links are treated as bidirectional for this query.
+func (g *Graph) ArticulationNodes() []uint32 {
+	g.lock.RLock()
+	links := g.liveLinks()
+	g.lock.RUnlock()
+
+	adjacent := make(map[uint32][]uint32)
+	for _, link := range links {
+		adjacent[link.From] = append(adjacent[link.From], link.To)
+		adjacent[link.To] = append(adjacent[link.To], link.From)
+	}
+
+	t := tarjan{
+		adjacent: adjacent,
+		order:    make(map[uint32]int),
+		low:      make(map[uint32]int),
+		result:   make(map[uint32]struct{}),
+	}
+	for node := range adjacent {
+		if _, visited := t.order[node]; !visited {
+			t.visit(node, node, true)
+		}
+	}
+
+	nodes := make([]uint32, 0, len(t.result))
+	for node := range t.result {
+		nodes = append(nodes, node)
+	}
+	slices.Sort(nodes)
+	return nodes
+}
+
+// tarjan holds the state of Tarjan's articulation point search.
+type tarjan struct {
+	adjacent map[uint32][]uint32
+	order    map[uint32]int
+	low      map[uint32]int
+	counter  int
+	result   map[uint32]struct{}
+}
+
+func (t *tarjan) visit(node, parent uint32, root bool) {
+	t.counter++
+	t.order[node] = t.counter
+	t.low[node] = t.counter
+
+	children := 0
+	for _, next := range t.adjacent[node] {
+		if next == parent && !root {
+			continue
+		}
+		if _, visited := t.order[next]; visited {
+			t.low[node] = min(t.low[node], t.order[next])
+			continue
+		}
+
+		children++
+		t.visit(next, node, false)
+		t.low[node] = min(t.low[node], t.low[next])
+		if !root && t.low[next] >= t.order[node] {
+			t.result[node] = struct{}{}
+		}
+	}
+	if root && children > 1 {
+		t.result[node] = struct{}{}
+	}
+}
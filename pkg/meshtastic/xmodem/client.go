@@ -0,0 +1,289 @@
+// Package xmodem implements file transfer to and from the node's filesystem using the XModem
+// protocol carried in FromRadio/ToRadio frames.
+package xmodem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic"
+	"github.com/exepirit/meshtastic-go/pkg/meshtastic/proto"
+)
+
+// BlockSize is the maximum data size of a single XModem block.
+const BlockSize = 128
+
+const (
+	defaultTimeout    = 5 * time.Second
+	defaultMaxRetries = 10
+)
+
+var (
+	// ErrRejected is returned when the node refuses to open the file.
+	ErrRejected = errors.New("node rejected the transfer")
+	// ErrCancelled is returned when the node cancels the transfer.
+	ErrCancelled = errors.New("transfer cancelled by node")
+	// ErrTooManyRetries is returned when a block could not be transferred within the retry limit.
+	ErrTooManyRetries = errors.New("too many retransmissions")
+)
+
+// MatchXModem matches frames carrying XModem packets.
+// Use it to route frames to a Client through a meshtastic.FrameRouter.
+func MatchXModem(frame *proto.FromRadio) bool {
+	return frame.GetXmodemPacket() != nil
+}
+
+// ProgressFunc is called after each transferred block. Total is -1 if the size is unknown.
+type ProgressFunc func(done, total int64)
+
+// Client transfers files between the host and the node's filesystem.
+type Client struct {
+	// Transport is the connection to the radio.
+	Transport meshtastic.HardwareTransport
+	// Timeout is the time to wait for a reply to a single block. Defaults to 5 seconds.
+	Timeout time.Duration
+	// MaxRetries is the number of retransmissions of a block before the transfer is aborted.
+	// Defaults to 10.
+	MaxRetries int
+	// Progress is called after each transferred block, if set.
+	Progress ProgressFunc
+	// Notifications receives client notifications read from the transport during transfers,
+	// usually the Device. If nil, security warnings are logged with meshtastic.Logger.
+	Notifications meshtastic.NotificationReceiver
+}
+
+// List returns files stored on the node. The list is reported by the node during
+// the configuration exchange.
+func (c *Client) List(ctx context.Context) ([]*proto.FileInfo, error) {
+	config := meshtastic.Device{Transport: c.Transport}
+	state, err := config.Config().GetState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return state.Files, nil
+}
+
+// Download reads a file from the node and writes it into w.
+func (c *Client) Download(ctx context.Context, path string, w io.Writer) error {
+	request := &proto.XModem{Control: proto.XModem_STX, Seq: 0, Buffer: []byte(path)}
+	if err := c.send(ctx, request); err != nil {
+		return err
+	}
+	Logger.Debug("Download requested", "path", path)
+
+	var (
+		expectedSeq uint32 = 1
+		done        int64
+		retries     int
+	)
+	for {
+		packet, err := c.receive(ctx)
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			if retries++; retries > c.maxRetries() {
+				c.cancel(ctx)
+				return ErrTooManyRetries
+			}
+			if expectedSeq == 1 {
+				err = c.send(ctx, request)
+			} else {
+				err = c.sendControl(ctx, proto.XModem_NAK)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		switch packet.GetControl() {
+		case proto.XModem_SOH, proto.XModem_STX:
+			if packet.GetSeq() == expectedSeq-1 && expectedSeq > 1 {
+				// our ACK was lost and the node repeats the previous block
+				if err = c.sendControl(ctx, proto.XModem_ACK); err != nil {
+					return err
+				}
+				continue
+			}
+			if packet.GetSeq() != expectedSeq || uint32(CRC16(packet.GetBuffer())) != packet.GetCrc16() {
+				Logger.Debug("Bad block received", "seq", packet.GetSeq(), "expected", expectedSeq)
+				if retries++; retries > c.maxRetries() {
+					c.cancel(ctx)
+					return ErrTooManyRetries
+				}
+				if err = c.sendControl(ctx, proto.XModem_NAK); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if _, err = w.Write(packet.GetBuffer()); err != nil {
+				c.cancel(ctx)
+				return fmt.Errorf("failed to write file data: %w", err)
+			}
+			done += int64(len(packet.GetBuffer()))
+			expectedSeq++
+			retries = 0
+			c.progress(done, -1)
+			if err = c.sendControl(ctx, proto.XModem_ACK); err != nil {
+				return err
+			}
+		case proto.XModem_EOT:
+			Logger.Debug("Download complete", "path", path, "bytes", done)
+			return nil
+		case proto.XModem_NAK:
+			if expectedSeq == 1 {
+				return ErrRejected
+			}
+		case proto.XModem_CAN:
+			return ErrCancelled
+		}
+	}
+}
+
+// Upload writes data read from r into a file on the node. Size is used for progress
+// reporting only and may be -1 if unknown.
+func (c *Client) Upload(ctx context.Context, path string, r io.Reader, size int64) error {
+	err := c.exchange(ctx, &proto.XModem{Control: proto.XModem_SOH, Seq: 0, Buffer: []byte(path)})
+	if errors.Is(err, errNegative) {
+		return ErrRejected
+	}
+	if err != nil {
+		return err
+	}
+	Logger.Debug("Upload accepted", "path", path)
+
+	var (
+		seq  uint32 = 1
+		done int64
+		buf  = make([]byte, BlockSize)
+	)
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			block := append([]byte(nil), buf[:n]...)
+			err = c.exchange(ctx, &proto.XModem{
+				Control: proto.XModem_SOH,
+				Seq:     seq,
+				Crc16:   uint32(CRC16(block)),
+				Buffer:  block,
+			})
+			if err != nil {
+				c.cancel(ctx)
+				return err
+			}
+			seq++
+			done += int64(n)
+			c.progress(done, size)
+		}
+
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			c.cancel(ctx)
+			return fmt.Errorf("failed to read file data: %w", readErr)
+		}
+	}
+
+	if err = c.exchange(ctx, &proto.XModem{Control: proto.XModem_EOT}); err != nil {
+		return err
+	}
+	Logger.Debug("Upload complete", "path", path, "bytes", done)
+	return nil
+}
+
+// errNegative is returned by exchange when the node answers the file request with NAK.
+var errNegative = errors.New("negative acknowledgement")
+
+// exchange sends a packet and waits for ACK, retransmitting it on NAK or timeout.
+func (c *Client) exchange(ctx context.Context, packet *proto.XModem) error {
+	for retries := 0; ; retries++ {
+		if retries > c.maxRetries() {
+			return ErrTooManyRetries
+		}
+		if err := c.send(ctx, packet); err != nil {
+			return err
+		}
+
+		reply, err := c.receive(ctx)
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		switch reply.GetControl() {
+		case proto.XModem_ACK:
+			return nil
+		case proto.XModem_CAN:
+			return ErrCancelled
+		case proto.XModem_NAK:
+			if packet.GetSeq() == 0 && packet.GetControl() == proto.XModem_SOH {
+				return errNegative // the file cannot be opened, retrying will not help
+			}
+			Logger.Debug("Block rejected. Retransmitting", "seq", packet.GetSeq())
+		}
+	}
+}
+
+// receive waits for the next XModem packet from the node, ignoring other frames.
+func (c *Client) receive(ctx context.Context) (*proto.XModem, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		frame, err := c.Transport.ReceiveFromRadio(ctx)
+		if errors.Is(err, meshtastic.ErrInvalidPacketFormat) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if packet := frame.GetXmodemPacket(); packet != nil {
+			return packet, nil
+		}
+		meshtastic.HandleNotificationFrame(c.Notifications, frame)
+	}
+}
+
+func (c *Client) send(ctx context.Context, packet *proto.XModem) error {
+	err := c.Transport.SendToRadio(ctx, &proto.ToRadio{
+		PayloadVariant: &proto.ToRadio_XmodemPacket{XmodemPacket: packet},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send XModem packet: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) sendControl(ctx context.Context, control proto.XModem_Control) error {
+	return c.send(ctx, &proto.XModem{Control: control})
+}
+
+// cancel aborts the transfer on the node side. Errors are ignored as the transfer has already failed.
+func (c *Client) cancel(ctx context.Context) {
+	_ = c.sendControl(ctx, proto.XModem_CAN)
+}
+
+func (c *Client) progress(done, total int64) {
+	if c.Progress != nil {
+		c.Progress(done, total)
+	}
+}
+
+func (c *Client) maxRetries() int {
+	if c.MaxRetries <= 0 {
+		return defaultMaxRetries
+	}
+	return c.MaxRetries
+}